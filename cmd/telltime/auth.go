@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/auth"
+	"golang.org/x/term"
+)
+
+// runAuthCommand manages the password and the API token. The server reads
+// both when it starts, so it has to be restarted for changes to take effect.
+func runAuthCommand(config *conf.Config, db *sql.DB, queries *dbgen.Queries, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand (expected one of these values: set-password, disable, token)")
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "set-password":
+		password, err := readNewPassword()
+		if err != nil {
+			return err
+		}
+		if err = auth.SetPassword(ctx, db, password); err != nil {
+			return err
+		}
+		if _, err = auth.ReadAPIToken(config.APITokenPath, true); err != nil {
+			return fmt.Errorf("creating the API token: %v", err)
+		}
+
+		fmt.Printf("The password was set and the API token is in %s.\n", config.APITokenPath)
+		fmt.Println("Restart the server to require them.")
+	case "disable":
+		if err := auth.RemovePassword(ctx, db); err != nil {
+			return err
+		}
+		if err := os.Remove(config.APITokenPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing the API token: %v", err)
+		}
+
+		fmt.Println("Authentication was disabled. Restart the server to apply it.")
+	case "token":
+		fs := flag.NewFlagSet("auth token", flag.ContinueOnError)
+		rotate := fs.Bool("rotate", false, "Replace the token with a new one")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		enabled, err := auth.Enabled(ctx, queries)
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			return fmt.Errorf("authentication is disabled (set a password with `%s auth set-password` first)", conf.ProgramName)
+		}
+
+		var token string
+		if *rotate {
+			token, err = auth.RotateAPIToken(config.APITokenPath)
+		} else {
+			token, err = auth.ReadAPIToken(config.APITokenPath, true)
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(token)
+		if *rotate {
+			fmt.Fprintln(os.Stderr, "Restart the server to use the new token.")
+		}
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+
+	return nil
+}
+
+// readNewPassword asks for the password twice if stdin is a terminal and
+// reads a single line otherwise, so that the password can be piped in.
+func readNewPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("reading the password: %v", err)
+		}
+
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	prompt := func(text string) (string, error) {
+		fmt.Fprint(os.Stderr, text)
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+
+		return string(b), err
+	}
+
+	password, err := prompt("New password: ")
+	if err != nil {
+		return "", err
+	}
+	confirmation, err := prompt("Repeat the password: ")
+	if err != nil {
+		return "", err
+	}
+	if password != confirmation {
+		return "", fmt.Errorf("the passwords don't match")
+	}
+
+	return password, nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/services/auth"
+)
+
+const cliTimeLayout = "2006-01-02 15:04"
+
+// serverCommands talk to the running server instead of reading the database
+// directly, so main doesn't open it for them.
+var serverCommands = []string{"hook", "status"}
+
+// runCommand runs the subcommand in args and returns the exit code.
+func runCommand(config *conf.Config, db *sql.DB, queries *dbgen.Queries, args []string) int {
+	var err error
+
+	switch args[0] {
+	case "auth":
+		err = runAuthCommand(config, db, queries, args[1:])
+	case "edit":
+		err = runEditCommand(queries, args[1:])
+	case "hook":
+		err = runHookCommand(config, args[1:])
+	case "report":
+		err = runReportCommand(config, queries, args[1:])
+	case "status":
+		err = runStatusCommand(config, args[1:])
+	case "sync":
+		err = runSyncCommand(config, queries, args[1:])
+	default:
+		err = fmt.Errorf("unknown command %q (expected one of these values: auth, edit, hook, report, status, sync)", args[0])
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+
+	return 0
+}
+
+func runEditCommand(queries *dbgen.Queries, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand (expected one of these values: add, relabel, split, delete, list, revert)")
+	}
+
+	ctx := context.Background()
+	fs := flag.NewFlagSet("edit "+args[0], flag.ContinueOnError)
+
+	rawStart := fs.String("start", "", fmt.Sprintf("The start of the range (format: %q)", cliTimeLayout))
+	rawEnd := fs.String("end", "", fmt.Sprintf("The end of the range (format: %q)", cliTimeLayout))
+	duration := fs.Duration("duration", 0, "The length of the range (e.g. 45m). Used instead of -end.")
+	eventID := fs.Int64("event", 0, "The ID of a recorded event. Used instead of -start and -end.")
+	deviceID := fs.String("device", "", "The ID of the device whose time is edited (default: this machine)")
+	rawAt := fs.String("at", "", fmt.Sprintf("Where to split the event (format: %q)", cliTimeLayout))
+	label := fs.String("label", "", "The label to report the time under")
+	category := fs.String("category", "", "The category to report the time under")
+	note := fs.String("note", "", "A note explaining the edit")
+	editID := fs.Int64("id", 0, "The ID of the edit to revert")
+	limit := fs.Int64("n", 20, "How many edits to list")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	parseRange := func() (activity.EditParams, error) {
+		params := activity.EditParams{
+			DeviceID: *deviceID,
+			Label:    *label,
+			Category: *category,
+			Note:     *note,
+			Source:   activity.EditSourceCLI,
+		}
+
+		if *eventID != 0 {
+			start, end, eventDeviceID, err := activity.EventRange(ctx, queries, *eventID)
+			params.DeviceID, params.Start, params.End = eventDeviceID, start, end
+			return params, err
+		}
+
+		start, err := parseCLITime(*rawStart)
+		if err != nil {
+			return params, err
+		}
+		params.Start = start
+
+		if *duration != 0 {
+			params.End = start.Add(*duration)
+			return params, nil
+		}
+
+		params.End, err = parseCLITime(*rawEnd)
+		return params, err
+	}
+
+	var edit dbgen.EventEdit
+	var err error
+
+	switch args[0] {
+	case "add":
+		var params activity.EditParams
+		if params, err = parseRange(); err == nil {
+			edit, err = activity.AddManualEntry(ctx, queries, params)
+		}
+	case "relabel":
+		var params activity.EditParams
+		if params, err = parseRange(); err == nil {
+			edit, err = activity.Relabel(ctx, queries, params)
+		}
+	case "split":
+		var params activity.EditParams
+		var at time.Time
+		if params, err = parseRange(); err == nil {
+			if at, err = parseCLITime(*rawAt); err == nil {
+				edit, err = activity.Split(ctx, queries, at, params)
+			}
+		}
+	case "delete":
+		var params activity.EditParams
+		if params, err = parseRange(); err == nil {
+			edit, err = activity.DeleteRange(ctx, queries, params)
+		}
+	case "revert":
+		if err = activity.RevertEdit(ctx, queries, *editID); err == nil {
+			fmt.Printf("reverted edit %d\n", *editID)
+		}
+		return err
+	case "list":
+		return printEdits(ctx, queries, *limit)
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("saved edit %d (%s)\n", edit.ID, edit.Kind)
+
+	return nil
+}
+
+func printEdits(ctx context.Context, queries *dbgen.Queries, limit int64) error {
+	edits, err := queries.GetEventEdits(ctx, limit)
+	if err != nil {
+		return err
+	}
+	devices, err := queries.GetDevices(ctx)
+	if err != nil {
+		return err
+	}
+	names := activity.DeviceNames(devices)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tKIND\tDEVICE\tSTART\tDURATION\tLABEL\tCATEGORY\tSOURCE\tREVERTED")
+	for _, e := range edits {
+		device := names[e.DeviceID.String]
+		if device == "" {
+			device = e.DeviceID.String
+		}
+
+		fmt.Fprintf(
+			tw,
+			"%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%v\n",
+			e.ID,
+			e.Kind,
+			device,
+			time.Unix(e.StartTime, 0).Format(cliTimeLayout),
+			time.Duration(e.Duration)*time.Second,
+			e.Label.String,
+			e.Category.String,
+			e.Source,
+			e.RevertedAt.Valid,
+		)
+	}
+
+	return tw.Flush()
+}
+
+// serverURL returns the URL of path on the server running on this machine.
+func serverURL(config *conf.Config, path string) string {
+	host := config.BindAddress
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+
+	return fmt.Sprintf("http://%s%s", net.JoinHostPort(host, strconv.Itoa(config.Port)), path)
+}
+
+// newServerRequest creates a request to the server running on this machine.
+// The API token is sent if there is one.
+func newServerRequest(config *conf.Config, method string, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, serverURL(config, path), body)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := auth.ReadAPIToken(config.APITokenPath, false)
+	if err == nil {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading the API token: %v", err)
+	}
+
+	return req, nil
+}
+
+func parseCLITime(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, fmt.Errorf("%w: missing time", activity.ErrInvalidEdit)
+	}
+
+	if t, err := time.ParseInLocation(cliTimeLayout, raw, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %q is not a valid time (expected format: %q)", activity.ErrInvalidEdit, raw, cliTimeLayout)
+}
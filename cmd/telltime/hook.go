@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+)
+
+// hookTimeout keeps a stopped or busy server from holding up the prompt.
+const hookTimeout = 2 * time.Second
+
+// hookCommandEnv is how the hooks pass the command to `telltime hook`. Unlike
+// the arguments of a process, its environment can't be read by other users.
+const hookCommandEnv = "TELLTIME_COMMAND"
+
+// The hooks remember the command, the working directory and the start time
+// right before the command runs and report them together with the exit
+// status once the prompt is about to be drawn again. The report runs in the
+// background so that it doesn't delay the prompt.
+const zshHook = `zmodload zsh/datetime
+
+_telltime_preexec() {
+	_telltime_command=$1
+	_telltime_cwd=$PWD
+	_telltime_start=$EPOCHREALTIME
+}
+
+_telltime_precmd() {
+	local exit_status=$?
+	[[ -z $_telltime_command ]] && return
+	%[3]s="$_telltime_command" %[1]s -port %[2]d%[4]s hook -shell zsh -cwd "$_telltime_cwd" \
+		-start "$_telltime_start" -exit "$exit_status" >/dev/null 2>&1 &!
+	unset _telltime_command _telltime_cwd _telltime_start
+}
+
+autoload -Uz add-zsh-hook
+add-zsh-hook preexec _telltime_preexec
+add-zsh-hook precmd _telltime_precmd
+`
+
+// Bash has no preexec hook, so a DEBUG trap is used instead. It fires before
+// every simple command, including the ones run by PROMPT_COMMAND, which is
+// why it only records the first command run after the prompt was drawn.
+const bashHook = `_telltime_preexec() {
+	[[ -n $COMP_LINE || -z $_telltime_at_prompt ]] && return
+	unset _telltime_at_prompt
+	_telltime_command=$BASH_COMMAND
+	_telltime_cwd=$PWD
+	_telltime_start=${EPOCHREALTIME:-$(date +%%s)}
+}
+
+_telltime_precmd() {
+	local exit_status=$?
+	if [[ -n $_telltime_command ]]; then
+		(%[3]s="$_telltime_command" %[1]s -port %[2]d%[4]s hook -shell bash -cwd "$_telltime_cwd" \
+			-start "$_telltime_start" -exit "$exit_status" >/dev/null 2>&1 &)
+	fi
+	unset _telltime_command _telltime_cwd _telltime_start
+}
+
+trap '_telltime_preexec' DEBUG
+PROMPT_COMMAND="_telltime_precmd${PROMPT_COMMAND:+;$PROMPT_COMMAND};_telltime_at_prompt=1"
+`
+
+// runHookCommand reports a command that finished running in a shell to the
+// server, which attaches it to the window that was focused at the time.
+// `hook init <shell>` prints the code that installs the hooks. Unless
+// -record-command-lines is set, only the name of the program is sent.
+func runHookCommand(config *conf.Config, args []string) error {
+	if len(args) > 0 && args[0] == "init" {
+		if len(args) < 2 {
+			return fmt.Errorf("missing shell (expected one of these values: zsh, bash)")
+		}
+
+		return printHook(config, args[1])
+	}
+
+	fs := flag.NewFlagSet("hook", flag.ContinueOnError)
+
+	command := fs.String("command", os.Getenv(hookCommandEnv), "The command that was run (default: $"+hookCommandEnv+")")
+	cwd := fs.String("cwd", "", "The directory the command was run in (default: the current directory)")
+	rawStart := fs.String("start", "", "When the command started, in seconds since the Unix epoch (default: now)")
+	exitStatus := fs.Int("exit", 0, "The exit status of the command")
+	shell := fs.String("shell", "", "The shell the command was run in")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	fields := strings.Fields(*command)
+	if len(fields) == 0 {
+		return fmt.Errorf("missing command")
+	}
+	if !config.RecordCommandLines {
+		*command = fields[0]
+	}
+
+	req := map[string]any{
+		"end":     time.Now().UnixMilli(),
+		"command": *command,
+		"cwd":     *cwd,
+		"shell":   *shell,
+	}
+
+	if *cwd == "" {
+		if dir, err := os.Getwd(); err == nil {
+			req["cwd"] = dir
+		}
+	}
+
+	if *rawStart != "" {
+		// EPOCHREALTIME uses the decimal separator of the current locale.
+		secs, err := strconv.ParseFloat(strings.Replace(*rawStart, ",", ".", 1), 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a valid start time", *rawStart)
+		}
+		req["start"] = int64(secs * 1000)
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "exit" {
+			req["exit_status"] = *exitStatus
+		}
+	})
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := newServerRequest(config, http.MethodPost, "/api/shell-events", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := http.Client{Timeout: hookTimeout}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("reporting the command: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("reporting the command: unexpected status %q", resp.Status)
+	}
+
+	return nil
+}
+
+func printHook(config *conf.Config, shell string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	executable = "'" + strings.ReplaceAll(executable, "'", `'\''`) + "'"
+
+	// The hooks only send whole command lines if they're recorded.
+	var extraFlags string
+	if config.RecordCommandLines {
+		extraFlags = " -record-command-lines"
+	}
+
+	switch shell {
+	case "zsh":
+		fmt.Printf(zshHook, executable, config.Port, hookCommandEnv, extraFlags)
+	case "bash":
+		fmt.Printf(bashHook, executable, config.Port, hookCommandEnv, extraFlags)
+	default:
+		return fmt.Errorf("unsupported shell %q (expected one of these values: zsh, bash)", shell)
+	}
+
+	return nil
+}
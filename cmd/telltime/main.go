@@ -1,16 +1,30 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/bnuredini/telltime/internal/conf"
 	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/migrations"
 	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/services/auth"
+	"github.com/bnuredini/telltime/internal/services/devicesync"
+	"github.com/bnuredini/telltime/internal/services/reports"
+	"github.com/bnuredini/telltime/internal/services/settings"
 	"github.com/bnuredini/telltime/internal/templates"
 
 	_ "modernc.org/sqlite"
@@ -21,28 +35,77 @@ type universe struct {
 	Queries         *dbgen.Queries
 	Config          *conf.Config
 	TemplateManager *templates.Manager
+	APIToken        string
 }
 
-// TOOD: Report an error if the user tries to start the server more than once.
+// shutdownTimeout is how long in-flight requests get to finish when the
+// server is shutting down.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
+	os.Exit(run())
+}
+
+// run starts the server and the tracker and blocks until one of them fails or
+// the process is asked to stop. Everything is shut down in order before it
+// returns the exit code: first the server, then the tracker, which saves what
+// it has recorded, and finally the database.
+//
+// TOOD: Report an error if the user tries to start the server more than once.
+func run() int {
 	config, err := conf.Init()
 	if err != nil {
-		log.Fatalf("failed to parse the config: %v", err)
+		log.Printf("failed to parse the config: %v", err)
+		return 1
 	}
 
 	logFile := setUpLogging(&config)
 	defer logFile.Close()
 
+	if slices.Contains(serverCommands, flag.Arg(0)) {
+		return runCommand(&config, nil, nil, flag.Args())
+	}
+
 	dbConn, err := openDB(config.DBConnStr)
 	if err != nil {
-		log.Fatal(err)
+		log.Print(err)
+		return 1
 	}
 	defer dbConn.Close()
 
+	if err = migrations.Up(dbConn); err != nil {
+		log.Printf("failed to migrate the database: %v", err)
+		return 1
+	}
+
 	queries := dbgen.New(dbConn)
+
+	if err = settings.Load(context.Background(), queries, &config); err != nil {
+		log.Printf("failed to load the settings: %v", err)
+		return 1
+	}
+
+	// The hostname of the device is refreshed in case it changed since the
+	// last start.
+	if _, err = activity.LocalDevice(context.Background(), queries, time.Now()); err != nil {
+		log.Printf("failed to read the device: %v", err)
+		return 1
+	}
+
+	if flag.NArg() > 0 {
+		return runCommand(&config, dbConn, queries, flag.Args())
+	}
+
+	apiToken, err := loadAPIToken(context.Background(), queries, &config)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
 	templateManager, err := templates.NewManager()
 	if err != nil {
-		log.Fatal(err)
+		log.Print(err)
+		return 1
 	}
 
 	uni := &universe{
@@ -50,19 +113,123 @@ func main() {
 		Queries:         queries,
 		Config:          &config,
 		TemplateManager: templateManager,
+		APIToken:        apiToken,
 	}
 
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	srv := newServer(uni)
+	serverErrC := make(chan error, 1)
 	go func() {
-		version, buildTime := conf.VersionInfo()
+		buildTime, version := conf.VersionInfo()
 		slog.Info("staritng the server", "version", version, "buildTime", buildTime)
 
-		if err = startServer(uni); err != nil {
-			slog.Error("failed to serve", "err", err)
-			os.Exit(1)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			serverErrC <- err
 		}
 	}()
 
-	activity.Init(dbConn, &config)
+	// The tracker isn't stopped by the signal directly so that it only saves
+	// once the server doesn't handle any more requests.
+	trackerCtx, stopTracker := context.WithCancel(context.Background())
+	defer stopTracker()
+
+	trackerErrC := make(chan error, 1)
+	go func() {
+		trackerErrC <- activity.Run(trackerCtx, dbConn, &config)
+	}()
+
+	// Syncing and writing reports stop together with the tracker so that a
+	// push or a report in progress doesn't outlive the database.
+	syncDone := make(chan struct{})
+	go func() {
+		defer close(syncDone)
+		if config.SyncServer != "" {
+			devicesync.Run(trackerCtx, dbConn, &config)
+		}
+	}()
+
+	reportsDone := make(chan struct{})
+	go func() {
+		defer close(reportsDone)
+		if len(config.ReportPeriods) > 0 {
+			reports.Run(trackerCtx, dbConn, &config)
+		}
+	}()
+
+	exitCode := 0
+	trackerStopped := false
+
+	select {
+	case <-signalCtx.Done():
+		slog.Info("received a termination signal; shutting down")
+	case err := <-serverErrC:
+		slog.Error("failed to serve", "err", err)
+		exitCode = 1
+	case err := <-trackerErrC:
+		slog.Error("the tracker stopped", "err", err)
+		exitCode = 1
+		trackerStopped = true
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("failed to shut down the server", "err", err)
+		exitCode = 1
+	}
+
+	stopTracker()
+	if !trackerStopped {
+		if err := <-trackerErrC; err != nil {
+			slog.Error("failed to stop the tracker", "err", err)
+			exitCode = 1
+		}
+	}
+	<-syncDone
+	<-reportsDone
+
+	return exitCode
+}
+
+// loadAPIToken returns the API token if a password is set and an empty string
+// otherwise. Without a password, the server may only listen on the loopback
+// address, since anyone who can reach it could see the recorded activity.
+func loadAPIToken(ctx context.Context, queries *dbgen.Queries, config *conf.Config) (string, error) {
+	enabled, err := auth.Enabled(ctx, queries)
+	if err != nil {
+		return "", fmt.Errorf("checking whether authentication is enabled: %v", err)
+	}
+
+	if !enabled {
+		if !isLoopback(config.BindAddress) {
+			return "", fmt.Errorf(
+				"refusing to listen on %q without a password (set one with `%s auth set-password`)",
+				config.BindAddress,
+				conf.ProgramName,
+			)
+		}
+
+		return "", nil
+	}
+
+	token, err := auth.ReadAPIToken(config.APITokenPath, true)
+	if err != nil {
+		return "", fmt.Errorf("reading the API token: %v", err)
+	}
+
+	return token, nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
 
 func openDB(dbConnStr string) (*sql.DB, error) {
@@ -78,16 +245,23 @@ func openDB(dbConnStr string) (*sql.DB, error) {
 	return dbConn, nil
 }
 
-func startServer(uni *universe) error {
-	srv := http.Server{
-		Addr:    fmt.Sprintf(":%d", uni.Config.Port),
+func newServer(uni *universe) *http.Server {
+	// Server-sent event streams only end when their request's context is
+	// canceled, so the contexts of all requests are canceled once the server
+	// starts shutting down. Otherwise, Shutdown would wait for the streams
+	// until it timed out.
+	baseCtx, cancelBaseCtx := context.WithCancel(context.Background())
+
+	srv := &http.Server{
+		Addr:    net.JoinHostPort(uni.Config.BindAddress, strconv.Itoa(uni.Config.Port)),
 		Handler: routes(uni),
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
 	}
-	if err := srv.ListenAndServe(); err != nil {
-		return err
-	}
+	srv.RegisterOnShutdown(cancelBaseCtx)
 
-	return nil
+	return srv
 }
 
 func setUpLogging(config *conf.Config) *os.File {
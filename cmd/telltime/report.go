@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/services/auth"
+	"github.com/bnuredini/telltime/internal/services/reports"
+	"github.com/bnuredini/telltime/internal/services/team"
+)
+
+// runReportCommand writes the Markdown report of a week or a month, the same
+// one that -report-periods writes on a schedule. With -team-format, the time
+// spent in each category during a week is written as a signed document that
+// can be shared with the team; it has nothing but the totals.
+func runReportCommand(config *conf.Config, queries *dbgen.Queries, args []string) error {
+	ctx := context.Background()
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+
+	teamFormat := fs.Bool("team-format", false, "Write a signed JSON document with the category totals that can be shared with the team")
+	period := fs.String("period", conf.ReportPeriodWeekly, fmt.Sprintf("The period to report on (possible values: %s). Team reports are always weekly.", strings.Join(conf.ReportPeriods, ", ")))
+	rawDate := fs.String("date", "", "Any day of the period to report on (format: \"2006-01-02\"). The previous period is used without it.")
+	person := fs.String("person", defaultPerson(), "The name the team report is sent under")
+	out := fs.String("out", "", "The file to write the report to, e.g. in a shared folder. It's written to stdout without it.")
+	publish := fs.String("publish", "", "The URL of a telltime server to send the team report to (e.g. http://team-server:8000). The API token in -sync-token-path is sent if there is one.")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if !*teamFormat && *publish != "" {
+		return fmt.Errorf("only team reports can be published (see -team-format)")
+	}
+	if *teamFormat && *period != conf.ReportPeriodWeekly {
+		return fmt.Errorf("team reports are always weekly")
+	}
+
+	now := time.Now()
+	var date time.Time
+	if *rawDate != "" {
+		t, err := time.ParseInLocation(team.DateLayout, *rawDate, time.Local)
+		if err != nil {
+			return fmt.Errorf("%q is not a valid date (expected format: %q)", *rawDate, team.DateLayout)
+		}
+		date = t
+	} else {
+		first, _ := reports.Bounds(*period, now)
+		date = first.AddDate(0, 0, -1)
+	}
+
+	opts := activity.OptionsFromConfig(config)
+
+	if !*teamFormat {
+		report, err := reports.Build(ctx, queries, *period, date, opts, now)
+		if err != nil {
+			return err
+		}
+		return writeOutput(*out, func(w io.Writer) error {
+			_, err := io.WriteString(w, report.Markdown())
+			return err
+		})
+	}
+
+	report, err := team.BuildReport(ctx, queries, *person, date, opts, now)
+	if err != nil {
+		return err
+	}
+
+	key, err := team.LoadOrCreateKey(config.TeamKeyPath)
+	if err != nil {
+		return fmt.Errorf("reading the team key: %v", err)
+	}
+	if err = team.Sign(report, key); err != nil {
+		return err
+	}
+	if err = team.Verify(report); err != nil {
+		return err
+	}
+
+	if *publish != "" {
+		token, err := auth.ReadAPIToken(config.SyncTokenPath, false)
+		if errors.Is(err, os.ErrNotExist) {
+			token = ""
+		} else if err != nil {
+			return fmt.Errorf("reading the sync token: %v", err)
+		}
+
+		if err = team.Publish(ctx, *publish, token, report); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "sent the report for the week of %s to %s\n", report.PeriodStart, *publish)
+
+		if *out == "" {
+			return nil
+		}
+	}
+
+	return writeOutput(*out, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	})
+}
+
+// writeOutput calls write with the file at path, or with stdout if path is
+// empty.
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func defaultPerson() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+
+	return os.Getenv("USER")
+}
@@ -8,14 +8,50 @@ import (
 )
 
 func routes(uni *universe) http.Handler {
-	httpHandler := httphandler.New(uni.DB, uni.Queries, uni.TemplateManager)
+	httpHandler := httphandler.New(uni.DB, uni.Queries, uni.Config, uni.TemplateManager)
+	httpHandler.APIToken = uni.APIToken
 
+	// GET patterns match HEAD requests too.
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", httpHandler.HomeGet)
-	mux.HandleFunc("/activity", httpHandler.ActivityGet)
-	mux.HandleFunc("/calendar/select", httpHandler.CalendarSelectGet)
-	mux.HandleFunc("/most-used-programs", httpHandler.MostUsedProgramsGet)
-	mux.Handle("/static/", http.FileServer(http.FS(ui.Files)))
+	mux.HandleFunc("GET /{$}", httpHandler.HomeGet)
+	mux.HandleFunc("GET /activity", httpHandler.ActivityGet)
+	mux.HandleFunc("POST /activity/edits", httpHandler.ActivityEditsPost)
+	mux.HandleFunc("POST /activity/edits/revert", httpHandler.ActivityEditRevertPost)
+	mux.HandleFunc("GET /heatmap", httpHandler.HeatmapGet)
+	mux.HandleFunc("GET /calendar/select", httpHandler.CalendarSelectGet)
+	mux.HandleFunc("GET /most-used-programs", httpHandler.MostUsedProgramsGet)
+	mux.HandleFunc("GET /coding-stats", httpHandler.CodingStatsGet)
+	mux.HandleFunc("GET /shell-stats", httpHandler.ShellStatsGet)
+	mux.HandleFunc("GET /settings", httpHandler.SettingsGet)
+	mux.HandleFunc("POST /settings", httpHandler.SettingsPost)
+	mux.HandleFunc("GET /login", httpHandler.LoginGet)
+	mux.HandleFunc("POST /login", httpHandler.LoginPost)
+	mux.HandleFunc("POST /logout", httpHandler.LogoutPost)
+	mux.HandleFunc("GET /team", httpHandler.TeamGet)
+	mux.HandleFunc("GET /docs", httpHandler.DocsGet)
+	mux.HandleFunc("GET /docs/{name}", httpHandler.DocsGet)
+	mux.HandleFunc("GET /live", httpHandler.LiveGet)
+	mux.HandleFunc("GET /metrics", httpHandler.MetricsGet)
+	mux.HandleFunc("GET /healthz", httpHandler.HealthzGet)
+	mux.HandleFunc("GET /status", httpHandler.StatusGet)
+	mux.HandleFunc("POST /api/browser/events", httpHandler.BrowserEventsPost)
+	mux.HandleFunc("POST /api/heartbeats", httpHandler.HeartbeatsPost)
+	mux.HandleFunc("POST /api/shell-events", httpHandler.ShellEventsPost)
+	mux.HandleFunc("POST /api/sync/events", httpHandler.SyncEventsPost)
+	mux.HandleFunc("GET /api/sync/devices", httpHandler.SyncDevicesGet)
+	mux.HandleFunc("POST /api/team/reports", httpHandler.TeamReportsPost)
+	mux.HandleFunc("GET /api/team/reports", httpHandler.TeamReportsGet)
+	mux.Handle("GET /static/", http.FileServer(http.FS(ui.Files)))
 
-	return mux
+	return httphandler.RequestID(
+		httphandler.LogRequests(
+			httpHandler.RecoverPanics(
+				httphandler.SecurityHeaders(
+					httpHandler.RejectCrossOrigin(
+						httpHandler.RequireAuth(httpHandler.WithErrorPages(mux)),
+					),
+				),
+			),
+		),
+	)
 }
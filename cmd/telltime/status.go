@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/httphandler"
+)
+
+const statusTimeout = 5 * time.Second
+
+// runStatusCommand prints the status reported by the running server. It fails
+// if the server can't be reached or if the tracker is degraded, so it can be
+// used in scripts and alerts.
+func runStatusCommand(config *conf.Config, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	printJSON := fs.Bool("json", false, "Print the status as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	req, err := newServerRequest(config, http.MethodGet, "/status", nil)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: statusTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("the server isn't reachable: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %q", resp.Status)
+	}
+
+	var status httphandler.Status
+	if err = json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return fmt.Errorf("decoding the status: %v", err)
+	}
+
+	if *printJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(status); err != nil {
+			return err
+		}
+	} else {
+		printStatus(&status)
+	}
+
+	if status.Status != httphandler.StatusOK {
+		return fmt.Errorf("the tracker is %s", status.Status)
+	}
+
+	return nil
+}
+
+func printStatus(status *httphandler.Status) {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "never"
+		}
+
+		return fmt.Sprintf("%s (%v ago)", t.Format(cliTimeLayout), time.Since(*t).Round(time.Second))
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Status:\t%s\n", status.Status)
+	fmt.Fprintf(tw, "Version:\t%s (built %s)\n", status.Version, status.BuildTime)
+	fmt.Fprintf(tw, "Uptime:\t%v\n", time.Duration(status.UptimeSecs)*time.Second)
+	fmt.Fprintf(tw, "Backend:\t%s\n", status.Backend)
+	if status.Paused {
+		fmt.Fprintf(tw, "Paused:\tyes (asleep or locked)\n")
+	}
+	if status.OnDesktop {
+		fmt.Fprintf(tw, "On the desktop:\tyes\n")
+	}
+	if status.MediaPlaying {
+		fmt.Fprintf(tw, "Media playing:\tyes\n")
+	}
+	fmt.Fprintf(tw, "Last window read:\t%s\n", formatTime(status.LastWindowRead))
+	fmt.Fprintf(tw, "Last save:\t%s\n", formatTime(status.LastSave))
+	fmt.Fprintf(tw, "Pending events:\t%d\n", status.PendingEvents)
+	fmt.Fprintf(tw, "Tracker errors:\t%d\n", status.TickErrors)
+	fmt.Fprintf(tw, "Failed saves:\t%d\n", status.FailedSaves)
+	fmt.Fprintf(tw, "Database size:\t%.1f MiB\n", float64(status.DBSizeBytes)/(1<<20))
+	fmt.Fprintf(tw, "Events:\t%d\n", status.EventCount)
+	if status.Sync != nil {
+		fmt.Fprintf(tw, "Sync server:\t%s\n", status.Sync.Server)
+		fmt.Fprintf(tw, "Last sync:\t%s\n", formatTime(status.Sync.LastSync))
+		fmt.Fprintf(tw, "Pushed events:\t%d\n", status.Sync.PushedEvents)
+		if status.Sync.LastError != "" {
+			fmt.Fprintf(tw, "Sync error:\t%s\n", status.Sync.LastError)
+		}
+	}
+	tw.Flush()
+
+	for _, problem := range status.Problems {
+		fmt.Printf("problem: %s\n", problem)
+	}
+}
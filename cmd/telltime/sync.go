@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/services/devicesync"
+)
+
+// runSyncCommand pushes the events the sync server doesn't have yet without
+// waiting for the server running on this machine to do it. `sync devices`
+// lists the devices whose events are in the database.
+func runSyncCommand(config *conf.Config, queries *dbgen.Queries, args []string) error {
+	ctx := context.Background()
+
+	if len(args) > 0 && args[0] == "devices" {
+		return printDevices(ctx, queries)
+	} else if len(args) > 0 {
+		return fmt.Errorf("unknown subcommand %q (expected one of these values: devices)", args[0])
+	}
+
+	pushed, err := devicesync.PushOnce(ctx, queries, config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("pushed %d events to %s\n", pushed, config.SyncServer)
+
+	return nil
+}
+
+func printDevices(ctx context.Context, queries *dbgen.Queries) error {
+	if _, err := activity.LocalDevice(ctx, queries, time.Now()); err != nil {
+		return err
+	}
+
+	devices, err := queries.GetDevices(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tHOSTNAME\tLOCAL\tLAST SEQ\tLAST SEEN")
+	for _, d := range devices {
+		lastSeen := "-"
+		if d.IsLocal == 0 {
+			lastSeen = time.Unix(d.LastSeenAt, 0).Format(cliTimeLayout)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%d\t%s\n", d.ID, d.Hostname, d.IsLocal == 1, d.LastSeq, lastSeen)
+	}
+
+	return tw.Flush()
+}
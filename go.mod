@@ -3,12 +3,15 @@ module github.com/bnuredini/telltime
 go 1.24.4
 
 require (
+	github.com/BurntSushi/xgb v0.0.0-20210121224620-deaf085860bc
 	github.com/BurntSushi/xgbutil v0.0.0-20190907113008-ad855c713046
+	github.com/godbus/dbus/v5 v5.1.0
+	github.com/yuin/goldmark v1.7.8
+	golang.org/x/term v0.35.0
 	modernc.org/sqlite v1.39.1
 )
 
 require (
-	github.com/BurntSushi/xgb v0.0.0-20210121224620-deaf085860bc // indirect
 	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
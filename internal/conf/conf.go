@@ -6,13 +6,13 @@ import (
 
 	"flag"
 	"fmt"
-	"strings"
 	"log"
 	"path/filepath"
 	"reflect"
-	"time"
 	"runtime"
 	"slices"
+	"strings"
+	"time"
 )
 
 var (
@@ -21,22 +21,77 @@ var (
 )
 
 type Config struct {
-	Port                int
-	DBConnStr           string `sensitive:"yes"`
-	LogToFile           bool
-	LogFilePath         string
-	LogLevel            int
-	RecordWindowTitles  bool
-	WindowCheckInterval int
-	SaveInterval        int
-	OS string
-	DisplayServer string
+	Port                int        `flag:"port"`
+	BindAddress         string     `flag:"bind-address"`
+	DBConnStr           string     `flag:"db-conn-str" sensitive:"yes"`
+	LogToFile           bool       `flag:"log-to-file"`
+	LogFilePath         string     `flag:"log-file-path"`
+	LogLevel            int        `flag:"log-level"`
+	RecordWindowTitles  bool       `flag:"record-window-titles"`
+	WindowCheckInterval int        `flag:"window-check-internal"`
+	SaveInterval        int        `flag:"save-interval"`
+	MinTitleDuration    int        `flag:"min-title-duration"`
+	RecordCommandLines  bool       `flag:"record-command-lines"`
+	RecordIncognitoTabs bool       `flag:"record-incognito-tabs"`
+	BrowserClasses      StringList `flag:"browser-classes"`
+	EditorClasses       StringList `flag:"editor-classes"`
+	HeartbeatTimeout    int        `flag:"heartbeat-timeout"`
+	JournalPath         string     `flag:"journal-path"`
+	RecordDesktop       bool       `flag:"record-desktop"`
+	DetectMediaPlayback bool       `flag:"detect-media-playback"`
+	ExcludedClasses     StringList `flag:"excluded-classes"`
+	DayStartHour        int        `flag:"day-start-hour"`
+	APITokenPath        string     `flag:"api-token-path"`
+	AcceptSync          bool       `flag:"accept-sync"`
+	SyncServer          string     `flag:"sync-server"`
+	SyncInterval        int        `flag:"sync-interval"`
+	SyncTokenPath       string     `flag:"sync-token-path"`
+	AcceptTeamReports   bool       `flag:"accept-team-reports"`
+	TeamKeyPath         string     `flag:"team-key-path"`
+	ReportPeriods       StringList `flag:"report-periods"`
+	ReportHour          int        `flag:"report-hour"`
+	ReportsDir          string     `flag:"reports-dir"`
+	ReportSendmail      string     `flag:"report-sendmail"`
+	ReportEmail         string     `flag:"report-email"`
+	ReportNotify        bool       `flag:"report-notify"`
+	Categories          []CategoryRule
+	OS                  string
+	DisplayServer       string
 }
 
 const (
 	ProgramName = "telltime"
 )
 
+// StringList is a flag value that holds a comma-separated list.
+type StringList []string
+
+func (l *StringList) String() string {
+	if l == nil {
+		return ""
+	}
+
+	return strings.Join(*l, ",")
+}
+
+func (l *StringList) Set(value string) error {
+	*l = StringList{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+
+	return nil
+}
+
+// CategoryRule assigns the time spent in windows of the given classes to a
+// category. Categories set through edits take precedence.
+type CategoryRule struct {
+	Category string   `json:"category"`
+	Classes  []string `json:"classes"`
+}
+
 const (
 	OSDarwin  = "darwin"
 	OSFreeBSD = "freebsd"
@@ -45,13 +100,26 @@ const (
 )
 
 const (
-	DisplayServerX = "X"
+	DisplayServerX       = "X"
 	DisplayServerWayland = "wayland"
 )
 
+const (
+	ReportPeriodWeekly  = "weekly"
+	ReportPeriodMonthly = "monthly"
+)
+
+// ReportPeriods are the periods that reports can be written for.
+var ReportPeriods = []string{ReportPeriodWeekly, ReportPeriodMonthly}
+
 var (
-	DefaultLogPath      string
-	DefaultDatabasePath string
+	DefaultLogPath       string
+	DefaultDatabasePath  string
+	DefaultJournalPath   string
+	DefaultAPITokenPath  string
+	DefaultSyncTokenPath string
+	DefaultTeamKeyPath   string
+	DefaultReportsDir    string
 )
 
 func init() {
@@ -74,6 +142,11 @@ func init() {
 
 	DefaultLogPath = filepath.Join(shareDir, fmt.Sprintf("%v.log", ProgramName))
 	DefaultDatabasePath = filepath.Join(shareDir, fmt.Sprintf("%v.db", ProgramName))
+	DefaultJournalPath = filepath.Join(shareDir, fmt.Sprintf("%v.journal", ProgramName))
+	DefaultAPITokenPath = filepath.Join(shareDir, "api-token")
+	DefaultSyncTokenPath = filepath.Join(shareDir, "sync-token")
+	DefaultTeamKeyPath = filepath.Join(shareDir, "team-key")
+	DefaultReportsDir = filepath.Join(shareDir, "reports")
 }
 
 func Init() (Config, error) {
@@ -82,54 +155,8 @@ func Init() (Config, error) {
 		return config, fmt.Errorf("failed to generate the default config: %v", err)
 	}
 
-	flag.IntVar(
-		&config.Port,
-		"port",
-		config.Port,
-		"The server port",
-	)
-	flag.StringVar(
-		&config.DBConnStr,
-		"db-conn-str",
-		config.DBConnStr,
-		"The database connection string",
-	)
-	flag.BoolVar(
-		&config.LogToFile,
-		"log-to-file",
-		config.LogToFile,
-		"Determines whether logs should be outputted to a file instead of stdout (default value: false)",
-	)
-	flag.StringVar(
-		&config.LogFilePath,
-		"log-file-path",
-		DefaultLogPath,
-		"The path to the file used to store program logs. Note that this value is only used if log-to-file is set to true.",
-	)
-	flag.IntVar(
-		&config.LogLevel,
-		"log-level",
-		config.LogLevel,
-		"Logging level (possible values: -4, 0, 4, 8)",
-	)
-	flag.BoolVar(
-		&config.RecordWindowTitles,
-		"record-window-titles",
-		config.RecordWindowTitles,
-		"Record window titles (default value: false). For privacy reasons, this is an opt-in feature.",
-	)
-	flag.IntVar(
-		&config.WindowCheckInterval,
-		"window-check-internal",
-		config.WindowCheckInterval,
-		"How often to check for window changes (in seconds)",
-	)
-	flag.IntVar(
-		&config.SaveInterval,
-		"save-interval",
-		config.SaveInterval,
-		"How often to persist the window change event in the database (in seconds)",
-	)
+	registerFlags(flag.CommandLine, &config)
+
 	displayVersion := flag.Bool(
 		"version",
 		false,
@@ -145,6 +172,28 @@ func Init() (Config, error) {
 
 	flag.Parse()
 
+	if config.DayStartHour < 0 || config.DayStartHour > 23 {
+		return Config{}, fmt.Errorf("%v is not a valid hour (expected a value between 0 and 23)", config.DayStartHour)
+	}
+
+	if config.ReportHour < 0 || config.ReportHour > 23 {
+		return Config{}, fmt.Errorf("%v is not a valid report hour (expected a value between 0 and 23)", config.ReportHour)
+	}
+
+	for _, period := range config.ReportPeriods {
+		if !slices.Contains(ReportPeriods, period) {
+			return Config{}, fmt.Errorf("%q is not a valid report period (expected one of these values: %v)", period, ReportPeriods)
+		}
+	}
+
+	if config.ReportSendmail != "" && config.ReportEmail == "" {
+		return Config{}, fmt.Errorf("-report-sendmail requires -report-email")
+	}
+
+	if config.SyncInterval <= 0 {
+		return Config{}, fmt.Errorf("%v is not a valid sync interval (expected a positive number of seconds)", config.SyncInterval)
+	}
+
 	if config.LogLevel != -4 && config.LogLevel != 0 && config.LogLevel != 4 && config.LogLevel != 8 {
 		err := fmt.Errorf(
 			"%v is not a valid log level (expected one of these values: -4, 0, 4, 8)",
@@ -152,7 +201,7 @@ func Init() (Config, error) {
 		)
 		return Config{}, err
 	}
-	
+
 	operatingSystem := runtime.GOOS
 	supportedOperatingSystems := []string{OSLinux, OSWindows, OSDarwin}
 	if !slices.Contains(supportedOperatingSystems, operatingSystem) {
@@ -163,13 +212,13 @@ func Init() (Config, error) {
 		)
 		return Config{}, err
 	}
-	
+
 	config.OS = operatingSystem
-	
+
 	if operatingSystem == OSLinux {
 		displayServer := os.Getenv("XDG_SESSION_TYPE")
 		supportedDisplayServers := []string{DisplayServerX, DisplayServerWayland}
-		
+
 		if strings.TrimSpace(displayServer) == "" {
 			log.Print("warning: display server is missing, defaulting to X")
 			displayServer = DisplayServerX
@@ -181,7 +230,7 @@ func Init() (Config, error) {
 			)
 			return Config{}, err
 		}
-		
+
 		config.DisplayServer = displayServer
 	}
 
@@ -190,6 +239,205 @@ func Init() (Config, error) {
 	return config, nil
 }
 
+// registerFlags defines a flag for every option in fs. The current values in
+// config are used as the defaults.
+func registerFlags(fs *flag.FlagSet, config *Config) {
+	fs.IntVar(
+		&config.Port,
+		"port",
+		config.Port,
+		"The server port",
+	)
+	fs.StringVar(
+		&config.BindAddress,
+		"bind-address",
+		config.BindAddress,
+		"The address the server listens on. Listening on anything other than the loopback address requires a password (see telltime auth set-password). An empty value means all addresses.",
+	)
+	fs.StringVar(
+		&config.DBConnStr,
+		"db-conn-str",
+		config.DBConnStr,
+		"The database connection string",
+	)
+	fs.BoolVar(
+		&config.LogToFile,
+		"log-to-file",
+		config.LogToFile,
+		"Determines whether logs should be outputted to a file instead of stdout (default value: false)",
+	)
+	fs.StringVar(
+		&config.LogFilePath,
+		"log-file-path",
+		DefaultLogPath,
+		"The path to the file used to store program logs. Note that this value is only used if log-to-file is set to true.",
+	)
+	fs.IntVar(
+		&config.LogLevel,
+		"log-level",
+		config.LogLevel,
+		"Logging level (possible values: -4, 0, 4, 8)",
+	)
+	fs.BoolVar(
+		&config.RecordWindowTitles,
+		"record-window-titles",
+		config.RecordWindowTitles,
+		"Record window titles (default value: false). For privacy reasons, this is an opt-in feature.",
+	)
+	fs.IntVar(
+		&config.WindowCheckInterval,
+		"window-check-internal",
+		config.WindowCheckInterval,
+		"How often to check for window changes (in seconds)",
+	)
+	fs.IntVar(
+		&config.SaveInterval,
+		"save-interval",
+		config.SaveInterval,
+		"How often to persist the window change event in the database (in seconds)",
+	)
+	fs.IntVar(
+		&config.MinTitleDuration,
+		"min-title-duration",
+		config.MinTitleDuration,
+		"How long a new window title has to last before it's recorded as a separate event (in seconds). Only used if record-window-titles is set to true.",
+	)
+	fs.BoolVar(
+		&config.RecordCommandLines,
+		"record-command-lines",
+		config.RecordCommandLines,
+		"Record the command lines of the programs that own the focused windows and of the commands reported by the shell hooks (default value: false). Without it, only the program names are recorded. For privacy reasons, this is an opt-in feature.",
+	)
+	fs.BoolVar(
+		&config.RecordIncognitoTabs,
+		"record-incognito-tabs",
+		config.RecordIncognitoTabs,
+		"Record the URLs and titles of private browsing tabs reported by the browser extension (default value: false)",
+	)
+	fs.Var(
+		&config.BrowserClasses,
+		"browser-classes",
+		"Comma-separated window classes of browsers whose time is broken down by the domains reported by the browser extension",
+	)
+	fs.Var(
+		&config.EditorClasses,
+		"editor-classes",
+		"Comma-separated window classes of editors. Heartbeats from editor plugins only count while one of these is focused.",
+	)
+	fs.IntVar(
+		&config.HeartbeatTimeout,
+		"heartbeat-timeout",
+		config.HeartbeatTimeout,
+		"How long a heartbeat from an editor plugin counts as coding time if no other heartbeat follows it (in seconds)",
+	)
+	fs.StringVar(
+		&config.JournalPath,
+		"journal-path",
+		DefaultJournalPath,
+		"The path to the file that keeps the events that haven't been saved to the database yet so that they survive a crash. Journaling is disabled if it's empty.",
+	)
+	fs.BoolVar(
+		&config.RecordDesktop,
+		"record-desktop",
+		config.RecordDesktop,
+		"Record the time spent on the desktop, with no window focused, as screen time (default value: false)",
+	)
+	fs.BoolVar(
+		&config.DetectMediaPlayback,
+		"detect-media-playback",
+		config.DetectMediaPlayback,
+		"Check whether a media player is playing through MPRIS so that watching a video or listening to music without touching the keyboard or the mouse still counts as being active (default value: true)",
+	)
+	fs.Var(
+		&config.ExcludedClasses,
+		"excluded-classes",
+		"Comma-separated window classes that aren't recorded. Time spent in them doesn't count as screen time.",
+	)
+	fs.IntVar(
+		&config.DayStartHour,
+		"day-start-hour",
+		config.DayStartHour,
+		"The hour at which a new day starts in the stats, so that working past midnight counts towards the previous day (possible values: 0-23)",
+	)
+	fs.StringVar(
+		&config.APITokenPath,
+		"api-token-path",
+		DefaultAPITokenPath,
+		"The path to the file with the API token, which is created once a password is set",
+	)
+	fs.BoolVar(
+		&config.AcceptSync,
+		"accept-sync",
+		config.AcceptSync,
+		"Accept events pushed by other devices and include them in the stats (default value: false)",
+	)
+	fs.StringVar(
+		&config.SyncServer,
+		"sync-server",
+		config.SyncServer,
+		"The URL of a telltime server that accepts synced events (e.g. http://desktop:8000). The recorded events are pushed to it whenever it's reachable. Syncing is disabled if it's empty.",
+	)
+	fs.IntVar(
+		&config.SyncInterval,
+		"sync-interval",
+		config.SyncInterval,
+		"How often to push new events to the sync server (in seconds)",
+	)
+	fs.StringVar(
+		&config.SyncTokenPath,
+		"sync-token-path",
+		DefaultSyncTokenPath,
+		"The path to the file with the API token of the sync server. It's only needed if the server has a password.",
+	)
+	fs.BoolVar(
+		&config.AcceptTeamReports,
+		"accept-team-reports",
+		config.AcceptTeamReports,
+		"Accept the weekly team reports of teammates and show them combined on the team page (default value: false)",
+	)
+	fs.StringVar(
+		&config.TeamKeyPath,
+		"team-key-path",
+		DefaultTeamKeyPath,
+		"The path to the file with the key that team reports are signed with. It's created with the first report.",
+	)
+	fs.Var(
+		&config.ReportPeriods,
+		"report-periods",
+		"Comma-separated periods to write reports for once they're over (possible values: weekly, monthly). No reports are written if it's empty.",
+	)
+	fs.IntVar(
+		&config.ReportHour,
+		"report-hour",
+		config.ReportHour,
+		"The hour at which the reports are written on the day after the period (possible values: 0-23)",
+	)
+	fs.StringVar(
+		&config.ReportsDir,
+		"reports-dir",
+		DefaultReportsDir,
+		"The directory the reports are written to",
+	)
+	fs.StringVar(
+		&config.ReportSendmail,
+		"report-sendmail",
+		config.ReportSendmail,
+		"A sendmail-compatible command that the reports are mailed with (e.g. /usr/sbin/sendmail). Requires -report-email.",
+	)
+	fs.StringVar(
+		&config.ReportEmail,
+		"report-email",
+		config.ReportEmail,
+		"The address the reports are mailed to",
+	)
+	fs.BoolVar(
+		&config.ReportNotify,
+		"report-notify",
+		config.ReportNotify,
+		"Show a desktop notification that links to every new report (default value: false)",
+	)
+}
+
 func getDefaultConfig() (Config, error) {
 	config := Config{}
 
@@ -211,10 +459,42 @@ func getDefaultConfig() (Config, error) {
 	}
 
 	config.Port = 8000
+	config.BindAddress = "127.0.0.1"
 	config.DBConnStr = fmt.Sprintf("file://%v/telltime.db", dbDir)
 	config.LogLevel = -4
 	config.WindowCheckInterval = int((5 * time.Second).Seconds())
 	config.SaveInterval = int((5 * time.Minute).Seconds())
+	config.MinTitleDuration = int((10 * time.Second).Seconds())
+	config.BrowserClasses = StringList{
+		"firefox",
+		"Firefox",
+		"Navigator",
+		"Google-chrome",
+		"Chromium",
+		"chromium",
+		"Brave-browser",
+		"Vivaldi-stable",
+		"librewolf",
+	}
+	config.ReportHour = 8
+	config.EditorClasses = StringList{
+		"Code",
+		"code",
+		"VSCodium",
+		"Emacs",
+		"emacs",
+		"neovide",
+		"Zed",
+		"dev.zed.Zed",
+		"jetbrains-idea",
+		"jetbrains-goland",
+		"jetbrains-pycharm",
+		"sublime_text",
+	}
+	config.HeartbeatTimeout = int((2 * time.Minute).Seconds())
+	config.DetectMediaPlayback = true
+	config.DayStartHour = 4
+	config.SyncInterval = int((5 * time.Minute).Seconds())
 
 	return config, nil
 }
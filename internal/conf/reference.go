@@ -0,0 +1,57 @@
+package conf
+
+import (
+	"flag"
+	"io"
+	"reflect"
+)
+
+// Option describes a command-line flag for the configuration reference.
+type Option struct {
+	Flag    string
+	Field   string
+	Default string
+	Usage   string
+}
+
+// Reference lists the options in the order of the fields of Config. Fields
+// without a flag tag can't be set from the command line and are left out. The
+// defaults of sensitive fields are left out too.
+func Reference() ([]Option, error) {
+	config, err := getDefaultConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	fs := flag.NewFlagSet(ProgramName, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerFlags(fs, &config)
+
+	var options []Option
+	configType := reflect.TypeOf(config)
+	for i := range configType.NumField() {
+		field := configType.Field(i)
+		name := field.Tag.Get("flag")
+		if name == "" {
+			continue
+		}
+
+		f := fs.Lookup(name)
+		if f == nil {
+			continue
+		}
+
+		option := Option{
+			Flag:    f.Name,
+			Field:   field.Name,
+			Default: f.DefValue,
+			Usage:   f.Usage,
+		}
+		if field.Tag.Get("sensitive") == "yes" {
+			option.Default = ""
+		}
+		options = append(options, option)
+	}
+
+	return options, nil
+}
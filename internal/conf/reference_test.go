@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestReferenceCoversEveryFlag(t *testing.T) {
+	options, err := Reference()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documented := make(map[string]bool)
+	for _, option := range options {
+		documented[option.Flag] = true
+	}
+
+	config, err := getDefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet(ProgramName, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerFlags(fs, &config)
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if !documented[f.Name] {
+			t.Errorf("-%s has no field with a matching flag tag", f.Name)
+		}
+	})
+	if got, want := len(options), len(documented); got != want {
+		t.Errorf("got %d options for %d flags", got, want)
+	}
+}
+
+func TestReferenceHidesSensitiveDefaults(t *testing.T) {
+	options, err := Reference()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, option := range options {
+		switch option.Field {
+		case "DBConnStr":
+			if option.Default != "" {
+				t.Errorf("got default %q for -%s, want none", option.Default, option.Flag)
+			}
+		case "DayStartHour":
+			if option.Default != "4" {
+				t.Errorf("got default %q for -%s, want 4", option.Default, option.Flag)
+			}
+		}
+	}
+}
@@ -8,10 +8,112 @@ import (
 	"database/sql"
 )
 
+type BrowserEvent struct {
+	ID        int64
+	StartTime int64
+	Browser   sql.NullString
+	Url       sql.NullString
+	Domain    sql.NullString
+	Title     sql.NullString
+	Audible   int64
+	Incognito int64
+}
+
+type Device struct {
+	ID          string
+	Hostname    string
+	IsLocal     int64
+	LastSeq     int64
+	FirstSeenAt int64
+	LastSeenAt  int64
+}
+
 type Event struct {
 	ID          int64
 	StartTime   int64
 	WindowClass string
 	WindowTitle sql.NullString
 	Duration    int64
+	Pid         sql.NullInt64
+	ExePath     sql.NullString
+	Cmdline     sql.NullString
+	IsOpen      int64
+	DeviceID    sql.NullString
+	Seq         sql.NullInt64
+}
+
+type EventEdit struct {
+	ID         int64
+	Kind       string
+	StartTime  int64
+	Duration   int64
+	Label      sql.NullString
+	Category   sql.NullString
+	Note       sql.NullString
+	Source     string
+	CreatedAt  int64
+	RevertedAt sql.NullInt64
+	DeviceID   sql.NullString
+}
+
+type Heartbeat struct {
+	ID       int64
+	Time     int64
+	Entity   string
+	Project  sql.NullString
+	Language sql.NullString
+	Branch   sql.NullString
+	Editor   sql.NullString
+	IsWrite  int64
+}
+
+type Password struct {
+	ID        int64
+	Hash      string
+	UpdatedAt int64
+}
+
+type Session struct {
+	TokenHash string
+	CreatedAt int64
+	ExpiresAt int64
+}
+
+type Setting struct {
+	Name      string
+	Value     string
+	UpdatedAt int64
+}
+
+type ShellEvent struct {
+	ID          int64
+	StartTime   int64
+	Duration    int64
+	Command     string
+	Cwd         sql.NullString
+	Repo        sql.NullString
+	ExitStatus  sql.NullInt64
+	Shell       sql.NullString
+	WindowClass sql.NullString
+}
+
+type SyncCursor struct {
+	ServerUrl string
+	LastSeq   int64
+	UpdatedAt int64
+}
+
+type TeamMember struct {
+	PublicKey   string
+	Person      string
+	FirstSeenAt int64
+}
+
+type TeamReport struct {
+	PublicKey   string
+	PeriodStart string
+	PeriodEnd   string
+	GeneratedAt int64
+	ReceivedAt  int64
+	Document    string
 }
@@ -10,8 +10,242 @@ import (
 	"database/sql"
 )
 
+const closeEvent = `-- name: CloseEvent :exec
+UPDATE event
+SET is_open = 0
+WHERE id = ?
+`
+
+func (q *Queries) CloseEvent(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, closeEvent, id)
+	return err
+}
+
+const countEvents = `-- name: CountEvents :one
+SELECT COUNT(*)
+FROM event
+`
+
+func (q *Queries) CountEvents(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countEvents)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countEventsByStart = `-- name: CountEventsByStart :one
+SELECT COUNT(*)
+FROM event
+WHERE start_time = ? AND window_class = ? AND is_open = 0
+`
+
+type CountEventsByStartParams struct {
+	StartTime   int64
+	WindowClass string
+}
+
+func (q *Queries) CountEventsByStart(ctx context.Context, arg CountEventsByStartParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countEventsByStart, arg.StartTime, arg.WindowClass)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteExpiredSessions = `-- name: DeleteExpiredSessions :exec
+DELETE FROM session
+WHERE expires_at <= ?
+`
+
+func (q *Queries) DeleteExpiredSessions(ctx context.Context, expiresAt int64) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredSessions, expiresAt)
+	return err
+}
+
+const deletePassword = `-- name: DeletePassword :exec
+DELETE FROM password
+`
+
+func (q *Queries) DeletePassword(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deletePassword)
+	return err
+}
+
+const deleteSession = `-- name: DeleteSession :exec
+DELETE FROM session
+WHERE token_hash = ?
+`
+
+func (q *Queries) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, deleteSession, tokenHash)
+	return err
+}
+
+const deleteSessions = `-- name: DeleteSessions :exec
+DELETE FROM session
+`
+
+func (q *Queries) DeleteSessions(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteSessions)
+	return err
+}
+
+const getActiveEventEditsInRange = `-- name: GetActiveEventEditsInRange :many
+SELECT id, kind, start_time, duration, label, category, note, source, created_at, reverted_at, device_id
+FROM event_edit
+WHERE reverted_at IS NULL
+	AND start_time < ?1
+	AND start_time + duration > ?2
+	AND (?3 IS NULL OR device_id = ?3)
+ORDER BY id
+`
+
+type GetActiveEventEditsInRangeParams struct {
+	EndTime   int64
+	StartTime int64
+	DeviceID  sql.NullString
+}
+
+func (q *Queries) GetActiveEventEditsInRange(ctx context.Context, arg GetActiveEventEditsInRangeParams) ([]EventEdit, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveEventEditsInRange, arg.EndTime, arg.StartTime, arg.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EventEdit
+	for rows.Next() {
+		var i EventEdit
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.StartTime,
+			&i.Duration,
+			&i.Label,
+			&i.Category,
+			&i.Note,
+			&i.Source,
+			&i.CreatedAt,
+			&i.RevertedAt,
+			&i.DeviceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBrowserEventsInRange = `-- name: GetBrowserEventsInRange :many
+SELECT id, start_time, browser, url, domain, title, audible, incognito
+FROM browser_event
+WHERE start_time < ?1
+	AND start_time >= COALESCE(
+		(SELECT MAX(b.start_time) FROM browser_event b WHERE b.start_time <= ?2),
+		0
+	)
+ORDER BY start_time
+`
+
+type GetBrowserEventsInRangeParams struct {
+	EndTime   int64
+	StartTime int64
+}
+
+func (q *Queries) GetBrowserEventsInRange(ctx context.Context, arg GetBrowserEventsInRangeParams) ([]BrowserEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getBrowserEventsInRange, arg.EndTime, arg.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowserEvent
+	for rows.Next() {
+		var i BrowserEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.StartTime,
+			&i.Browser,
+			&i.Url,
+			&i.Domain,
+			&i.Title,
+			&i.Audible,
+			&i.Incognito,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDevice = `-- name: GetDevice :one
+SELECT id, hostname, is_local, last_seq, first_seen_at, last_seen_at
+FROM device
+WHERE id = ?
+`
+
+func (q *Queries) GetDevice(ctx context.Context, id string) (Device, error) {
+	row := q.db.QueryRowContext(ctx, getDevice, id)
+	var i Device
+	err := row.Scan(
+		&i.ID,
+		&i.Hostname,
+		&i.IsLocal,
+		&i.LastSeq,
+		&i.FirstSeenAt,
+		&i.LastSeenAt,
+	)
+	return i, err
+}
+
+const getDevices = `-- name: GetDevices :many
+SELECT id, hostname, is_local, last_seq, first_seen_at, last_seen_at
+FROM device
+ORDER BY is_local DESC, hostname
+`
+
+func (q *Queries) GetDevices(ctx context.Context) ([]Device, error) {
+	rows, err := q.db.QueryContext(ctx, getDevices)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Device
+	for rows.Next() {
+		var i Device
+		if err := rows.Scan(
+			&i.ID,
+			&i.Hostname,
+			&i.IsLocal,
+			&i.LastSeq,
+			&i.FirstSeenAt,
+			&i.LastSeenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getEvent = `-- name: GetEvent :one
-SELECT id, start_time, window_class, window_title, duration
+SELECT id, start_time, window_class, window_title, duration, pid, exe_path, cmdline, is_open, device_id, seq
 FROM event
 WHERE id = ?
 `
@@ -25,10 +259,83 @@ func (q *Queries) GetEvent(ctx context.Context, id int64) (Event, error) {
 		&i.WindowClass,
 		&i.WindowTitle,
 		&i.Duration,
+		&i.Pid,
+		&i.ExePath,
+		&i.Cmdline,
+		&i.IsOpen,
+		&i.DeviceID,
+		&i.Seq,
+	)
+	return i, err
+}
+
+const getEventEdit = `-- name: GetEventEdit :one
+SELECT id, kind, start_time, duration, label, category, note, source, created_at, reverted_at, device_id
+FROM event_edit
+WHERE id = ?
+`
+
+func (q *Queries) GetEventEdit(ctx context.Context, id int64) (EventEdit, error) {
+	row := q.db.QueryRowContext(ctx, getEventEdit, id)
+	var i EventEdit
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.StartTime,
+		&i.Duration,
+		&i.Label,
+		&i.Category,
+		&i.Note,
+		&i.Source,
+		&i.CreatedAt,
+		&i.RevertedAt,
+		&i.DeviceID,
 	)
 	return i, err
 }
 
+const getEventEdits = `-- name: GetEventEdits :many
+SELECT id, kind, start_time, duration, label, category, note, source, created_at, reverted_at, device_id
+FROM event_edit
+ORDER BY id DESC
+LIMIT ?
+`
+
+func (q *Queries) GetEventEdits(ctx context.Context, limit int64) ([]EventEdit, error) {
+	rows, err := q.db.QueryContext(ctx, getEventEdits, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EventEdit
+	for rows.Next() {
+		var i EventEdit
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.StartTime,
+			&i.Duration,
+			&i.Label,
+			&i.Category,
+			&i.Note,
+			&i.Source,
+			&i.CreatedAt,
+			&i.RevertedAt,
+			&i.DeviceID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getEvents = `-- name: GetEvents :many
 SELECT start_time, window_class, window_title, duration
 FROM event
@@ -117,24 +424,842 @@ func (q *Queries) GetEventsByTime(ctx context.Context, arg GetEventsByTimeParams
 	return items, nil
 }
 
-const insertEvents = `-- name: InsertEvents :exec
-INSERT INTO event (start_time, window_class, window_title, duration)
-VALUES (?, ?, ?, ?)
+const getEventsInRange = `-- name: GetEventsInRange :many
+SELECT id, start_time, window_class, window_title, duration, pid, exe_path, cmdline, is_open, device_id, seq
+FROM event
+WHERE start_time < ?1 AND start_time + duration > ?2 AND is_open = 0
+	AND (?3 IS NULL OR device_id = ?3)
+ORDER BY start_time
 `
 
-type InsertEventsParams struct {
-	StartTime   int64
-	WindowClass string
-	WindowTitle sql.NullString
-	Duration    int64
+type GetEventsInRangeParams struct {
+	EndTime   int64
+	StartTime int64
+	DeviceID  sql.NullString
 }
 
-func (q *Queries) InsertEvents(ctx context.Context, arg InsertEventsParams) error {
-	_, err := q.db.ExecContext(ctx, insertEvents,
-		arg.StartTime,
-		arg.WindowClass,
-		arg.WindowTitle,
-		arg.Duration,
+func (q *Queries) GetEventsInRange(ctx context.Context, arg GetEventsInRangeParams) ([]Event, error) {
+	rows, err := q.db.QueryContext(ctx, getEventsInRange, arg.EndTime, arg.StartTime, arg.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Event
+	for rows.Next() {
+		var i Event
+		if err := rows.Scan(
+			&i.ID,
+			&i.StartTime,
+			&i.WindowClass,
+			&i.WindowTitle,
+			&i.Duration,
+			&i.Pid,
+			&i.ExePath,
+			&i.Cmdline,
+			&i.IsOpen,
+			&i.DeviceID,
+			&i.Seq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHeartbeatsInRange = `-- name: GetHeartbeatsInRange :many
+SELECT id, time, entity, project, language, branch, editor, is_write
+FROM heartbeat
+WHERE time BETWEEN ?1 AND ?2
+ORDER BY time
+`
+
+type GetHeartbeatsInRangeParams struct {
+	StartTime int64
+	EndTime   int64
+}
+
+func (q *Queries) GetHeartbeatsInRange(ctx context.Context, arg GetHeartbeatsInRangeParams) ([]Heartbeat, error) {
+	rows, err := q.db.QueryContext(ctx, getHeartbeatsInRange, arg.StartTime, arg.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Heartbeat
+	for rows.Next() {
+		var i Heartbeat
+		if err := rows.Scan(
+			&i.ID,
+			&i.Time,
+			&i.Entity,
+			&i.Project,
+			&i.Language,
+			&i.Branch,
+			&i.Editor,
+			&i.IsWrite,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLocalDevice = `-- name: GetLocalDevice :one
+SELECT id, hostname, is_local, last_seq, first_seen_at, last_seen_at
+FROM device
+WHERE is_local = 1
+`
+
+func (q *Queries) GetLocalDevice(ctx context.Context) (Device, error) {
+	row := q.db.QueryRowContext(ctx, getLocalDevice)
+	var i Device
+	err := row.Scan(
+		&i.ID,
+		&i.Hostname,
+		&i.IsLocal,
+		&i.LastSeq,
+		&i.FirstSeenAt,
+		&i.LastSeenAt,
+	)
+	return i, err
+}
+
+const getOpenEvents = `-- name: GetOpenEvents :many
+SELECT id, start_time, window_class, window_title, duration, pid, exe_path, cmdline, is_open, device_id, seq
+FROM event
+WHERE is_open = 1
+ORDER BY start_time
+`
+
+func (q *Queries) GetOpenEvents(ctx context.Context) ([]Event, error) {
+	rows, err := q.db.QueryContext(ctx, getOpenEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Event
+	for rows.Next() {
+		var i Event
+		if err := rows.Scan(
+			&i.ID,
+			&i.StartTime,
+			&i.WindowClass,
+			&i.WindowTitle,
+			&i.Duration,
+			&i.Pid,
+			&i.ExePath,
+			&i.Cmdline,
+			&i.IsOpen,
+			&i.DeviceID,
+			&i.Seq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPasswordHash = `-- name: GetPasswordHash :one
+SELECT hash
+FROM password
+WHERE id = 1
+`
+
+func (q *Queries) GetPasswordHash(ctx context.Context) (string, error) {
+	row := q.db.QueryRowContext(ctx, getPasswordHash)
+	var hash string
+	err := row.Scan(&hash)
+	return hash, err
+}
+
+const getSession = `-- name: GetSession :one
+SELECT token_hash, created_at, expires_at
+FROM session
+WHERE token_hash = ?1 AND expires_at > ?2
+`
+
+type GetSessionParams struct {
+	TokenHash string
+	Now       int64
+}
+
+func (q *Queries) GetSession(ctx context.Context, arg GetSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, getSession, arg.TokenHash, arg.Now)
+	var i Session
+	err := row.Scan(&i.TokenHash, &i.CreatedAt, &i.ExpiresAt)
+	return i, err
+}
+
+const getSettings = `-- name: GetSettings :many
+SELECT name, value, updated_at
+FROM setting
+ORDER BY name
+`
+
+func (q *Queries) GetSettings(ctx context.Context) ([]Setting, error) {
+	rows, err := q.db.QueryContext(ctx, getSettings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Setting
+	for rows.Next() {
+		var i Setting
+		if err := rows.Scan(&i.Name, &i.Value, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getShellEventsInRange = `-- name: GetShellEventsInRange :many
+SELECT id, start_time, duration, command, cwd, repo, exit_status, shell, window_class
+FROM shell_event
+WHERE start_time < ?2 AND start_time + duration >= ?1
+ORDER BY start_time
+`
+
+type GetShellEventsInRangeParams struct {
+	StartTime int64
+	EndTime   int64
+}
+
+func (q *Queries) GetShellEventsInRange(ctx context.Context, arg GetShellEventsInRangeParams) ([]ShellEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getShellEventsInRange, arg.StartTime, arg.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ShellEvent
+	for rows.Next() {
+		var i ShellEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.StartTime,
+			&i.Duration,
+			&i.Command,
+			&i.Cwd,
+			&i.Repo,
+			&i.ExitStatus,
+			&i.Shell,
+			&i.WindowClass,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSyncCursor = `-- name: GetSyncCursor :one
+SELECT last_seq
+FROM sync_cursor
+WHERE server_url = ?
+`
+
+func (q *Queries) GetSyncCursor(ctx context.Context, serverUrl string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getSyncCursor, serverUrl)
+	var last_seq int64
+	err := row.Scan(&last_seq)
+	return last_seq, err
+}
+
+const getTeamMember = `-- name: GetTeamMember :one
+SELECT public_key, person, first_seen_at
+FROM team_member
+WHERE public_key = ?
+`
+
+func (q *Queries) GetTeamMember(ctx context.Context, publicKey string) (TeamMember, error) {
+	row := q.db.QueryRowContext(ctx, getTeamMember, publicKey)
+	var i TeamMember
+	err := row.Scan(&i.PublicKey, &i.Person, &i.FirstSeenAt)
+	return i, err
+}
+
+const getTeamMemberByPerson = `-- name: GetTeamMemberByPerson :one
+SELECT public_key, person, first_seen_at
+FROM team_member
+WHERE person = ?
+`
+
+func (q *Queries) GetTeamMemberByPerson(ctx context.Context, person string) (TeamMember, error) {
+	row := q.db.QueryRowContext(ctx, getTeamMemberByPerson, person)
+	var i TeamMember
+	err := row.Scan(&i.PublicKey, &i.Person, &i.FirstSeenAt)
+	return i, err
+}
+
+const getTeamReportPeriods = `-- name: GetTeamReportPeriods :many
+SELECT DISTINCT period_start
+FROM team_report
+ORDER BY period_start DESC
+LIMIT ?
+`
+
+func (q *Queries) GetTeamReportPeriods(ctx context.Context, limit int64) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getTeamReportPeriods, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var period_start string
+		if err := rows.Scan(&period_start); err != nil {
+			return nil, err
+		}
+		items = append(items, period_start)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTeamReports = `-- name: GetTeamReports :many
+SELECT public_key, period_start, period_end, generated_at, received_at, document
+FROM team_report
+WHERE period_start = ?
+ORDER BY public_key
+`
+
+func (q *Queries) GetTeamReports(ctx context.Context, periodStart string) ([]TeamReport, error) {
+	rows, err := q.db.QueryContext(ctx, getTeamReports, periodStart)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TeamReport
+	for rows.Next() {
+		var i TeamReport
+		if err := rows.Scan(
+			&i.PublicKey,
+			&i.PeriodStart,
+			&i.PeriodEnd,
+			&i.GeneratedAt,
+			&i.ReceivedAt,
+			&i.Document,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnsyncedEvents = `-- name: GetUnsyncedEvents :many
+SELECT id, start_time, window_class, window_title, duration, pid, exe_path, cmdline, is_open, device_id, seq
+FROM event
+WHERE id > ?1 AND device_id = (SELECT id FROM device WHERE is_local = 1)
+ORDER BY id
+LIMIT ?2
+`
+
+type GetUnsyncedEventsParams struct {
+	AfterID int64
+	Limit   int64
+}
+
+func (q *Queries) GetUnsyncedEvents(ctx context.Context, arg GetUnsyncedEventsParams) ([]Event, error) {
+	rows, err := q.db.QueryContext(ctx, getUnsyncedEvents, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Event
+	for rows.Next() {
+		var i Event
+		if err := rows.Scan(
+			&i.ID,
+			&i.StartTime,
+			&i.WindowClass,
+			&i.WindowTitle,
+			&i.Duration,
+			&i.Pid,
+			&i.ExePath,
+			&i.Cmdline,
+			&i.IsOpen,
+			&i.DeviceID,
+			&i.Seq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertBrowserEvent = `-- name: InsertBrowserEvent :exec
+INSERT INTO browser_event (start_time, browser, url, domain, title, audible, incognito)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+type InsertBrowserEventParams struct {
+	StartTime int64
+	Browser   sql.NullString
+	Url       sql.NullString
+	Domain    sql.NullString
+	Title     sql.NullString
+	Audible   int64
+	Incognito int64
+}
+
+func (q *Queries) InsertBrowserEvent(ctx context.Context, arg InsertBrowserEventParams) error {
+	_, err := q.db.ExecContext(ctx, insertBrowserEvent,
+		arg.StartTime,
+		arg.Browser,
+		arg.Url,
+		arg.Domain,
+		arg.Title,
+		arg.Audible,
+		arg.Incognito,
+	)
+	return err
+}
+
+const insertDevice = `-- name: InsertDevice :exec
+INSERT INTO device (id, hostname, is_local, first_seen_at, last_seen_at)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type InsertDeviceParams struct {
+	ID          string
+	Hostname    string
+	IsLocal     int64
+	FirstSeenAt int64
+	LastSeenAt  int64
+}
+
+func (q *Queries) InsertDevice(ctx context.Context, arg InsertDeviceParams) error {
+	_, err := q.db.ExecContext(ctx, insertDevice,
+		arg.ID,
+		arg.Hostname,
+		arg.IsLocal,
+		arg.FirstSeenAt,
+		arg.LastSeenAt,
+	)
+	return err
+}
+
+const insertEventEdit = `-- name: InsertEventEdit :one
+INSERT INTO event_edit (kind, start_time, duration, label, category, note, source, created_at, device_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?9, (SELECT id FROM device WHERE is_local = 1)))
+RETURNING id, kind, start_time, duration, label, category, note, source, created_at, reverted_at, device_id
+`
+
+type InsertEventEditParams struct {
+	Kind      string
+	StartTime int64
+	Duration  int64
+	Label     sql.NullString
+	Category  sql.NullString
+	Note      sql.NullString
+	Source    string
+	CreatedAt int64
+	DeviceID  sql.NullString
+}
+
+func (q *Queries) InsertEventEdit(ctx context.Context, arg InsertEventEditParams) (EventEdit, error) {
+	row := q.db.QueryRowContext(ctx, insertEventEdit,
+		arg.Kind,
+		arg.StartTime,
+		arg.Duration,
+		arg.Label,
+		arg.Category,
+		arg.Note,
+		arg.Source,
+		arg.CreatedAt,
+		arg.DeviceID,
+	)
+	var i EventEdit
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.StartTime,
+		&i.Duration,
+		&i.Label,
+		&i.Category,
+		&i.Note,
+		&i.Source,
+		&i.CreatedAt,
+		&i.RevertedAt,
+		&i.DeviceID,
+	)
+	return i, err
+}
+
+const insertEvents = `-- name: InsertEvents :exec
+INSERT INTO event (start_time, window_class, window_title, duration, pid, exe_path, cmdline, device_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, (SELECT id FROM device WHERE is_local = 1))
+`
+
+type InsertEventsParams struct {
+	StartTime   int64
+	WindowClass string
+	WindowTitle sql.NullString
+	Duration    int64
+	Pid         sql.NullInt64
+	ExePath     sql.NullString
+	Cmdline     sql.NullString
+}
+
+func (q *Queries) InsertEvents(ctx context.Context, arg InsertEventsParams) error {
+	_, err := q.db.ExecContext(ctx, insertEvents,
+		arg.StartTime,
+		arg.WindowClass,
+		arg.WindowTitle,
+		arg.Duration,
+		arg.Pid,
+		arg.ExePath,
+		arg.Cmdline,
+	)
+	return err
+}
+
+const insertHeartbeat = `-- name: InsertHeartbeat :exec
+INSERT INTO heartbeat (time, entity, project, language, branch, editor, is_write)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+type InsertHeartbeatParams struct {
+	Time     int64
+	Entity   string
+	Project  sql.NullString
+	Language sql.NullString
+	Branch   sql.NullString
+	Editor   sql.NullString
+	IsWrite  int64
+}
+
+func (q *Queries) InsertHeartbeat(ctx context.Context, arg InsertHeartbeatParams) error {
+	_, err := q.db.ExecContext(ctx, insertHeartbeat,
+		arg.Time,
+		arg.Entity,
+		arg.Project,
+		arg.Language,
+		arg.Branch,
+		arg.Editor,
+		arg.IsWrite,
+	)
+	return err
+}
+
+const insertOpenEvent = `-- name: InsertOpenEvent :one
+INSERT INTO event (start_time, window_class, window_title, duration, pid, exe_path, cmdline, is_open, device_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, 1, (SELECT id FROM device WHERE is_local = 1))
+RETURNING id
+`
+
+type InsertOpenEventParams struct {
+	StartTime   int64
+	WindowClass string
+	WindowTitle sql.NullString
+	Duration    int64
+	Pid         sql.NullInt64
+	ExePath     sql.NullString
+	Cmdline     sql.NullString
+}
+
+func (q *Queries) InsertOpenEvent(ctx context.Context, arg InsertOpenEventParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertOpenEvent,
+		arg.StartTime,
+		arg.WindowClass,
+		arg.WindowTitle,
+		arg.Duration,
+		arg.Pid,
+		arg.ExePath,
+		arg.Cmdline,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertSession = `-- name: InsertSession :exec
+INSERT INTO session (token_hash, created_at, expires_at)
+VALUES (?, ?, ?)
+`
+
+type InsertSessionParams struct {
+	TokenHash string
+	CreatedAt int64
+	ExpiresAt int64
+}
+
+func (q *Queries) InsertSession(ctx context.Context, arg InsertSessionParams) error {
+	_, err := q.db.ExecContext(ctx, insertSession, arg.TokenHash, arg.CreatedAt, arg.ExpiresAt)
+	return err
+}
+
+const insertShellEvent = `-- name: InsertShellEvent :exec
+INSERT INTO shell_event (start_time, duration, command, cwd, repo, exit_status, shell, window_class)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type InsertShellEventParams struct {
+	StartTime   int64
+	Duration    int64
+	Command     string
+	Cwd         sql.NullString
+	Repo        sql.NullString
+	ExitStatus  sql.NullInt64
+	Shell       sql.NullString
+	WindowClass sql.NullString
+}
+
+func (q *Queries) InsertShellEvent(ctx context.Context, arg InsertShellEventParams) error {
+	_, err := q.db.ExecContext(ctx, insertShellEvent,
+		arg.StartTime,
+		arg.Duration,
+		arg.Command,
+		arg.Cwd,
+		arg.Repo,
+		arg.ExitStatus,
+		arg.Shell,
+		arg.WindowClass,
+	)
+	return err
+}
+
+const insertSyncedEvent = `-- name: InsertSyncedEvent :execrows
+INSERT INTO event (start_time, window_class, window_title, duration, exe_path, cmdline, device_id, seq)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (device_id, seq) DO NOTHING
+`
+
+type InsertSyncedEventParams struct {
+	StartTime   int64
+	WindowClass string
+	WindowTitle sql.NullString
+	Duration    int64
+	ExePath     sql.NullString
+	Cmdline     sql.NullString
+	DeviceID    sql.NullString
+	Seq         sql.NullInt64
+}
+
+func (q *Queries) InsertSyncedEvent(ctx context.Context, arg InsertSyncedEventParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, insertSyncedEvent,
+		arg.StartTime,
+		arg.WindowClass,
+		arg.WindowTitle,
+		arg.Duration,
+		arg.ExePath,
+		arg.Cmdline,
+		arg.DeviceID,
+		arg.Seq,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const insertTeamMember = `-- name: InsertTeamMember :exec
+INSERT INTO team_member (public_key, person, first_seen_at)
+VALUES (?, ?, ?)
+`
+
+type InsertTeamMemberParams struct {
+	PublicKey   string
+	Person      string
+	FirstSeenAt int64
+}
+
+func (q *Queries) InsertTeamMember(ctx context.Context, arg InsertTeamMemberParams) error {
+	_, err := q.db.ExecContext(ctx, insertTeamMember, arg.PublicKey, arg.Person, arg.FirstSeenAt)
+	return err
+}
+
+const revertEventEdit = `-- name: RevertEventEdit :execrows
+UPDATE event_edit
+SET reverted_at = ?
+WHERE id = ? AND reverted_at IS NULL
+`
+
+type RevertEventEditParams struct {
+	RevertedAt sql.NullInt64
+	ID         int64
+}
+
+func (q *Queries) RevertEventEdit(ctx context.Context, arg RevertEventEditParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revertEventEdit, arg.RevertedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateDevice = `-- name: UpdateDevice :exec
+UPDATE device
+SET hostname = ?, last_seq = ?, last_seen_at = ?
+WHERE id = ?
+`
+
+type UpdateDeviceParams struct {
+	Hostname   string
+	LastSeq    int64
+	LastSeenAt int64
+	ID         string
+}
+
+func (q *Queries) UpdateDevice(ctx context.Context, arg UpdateDeviceParams) error {
+	_, err := q.db.ExecContext(ctx, updateDevice,
+		arg.Hostname,
+		arg.LastSeq,
+		arg.LastSeenAt,
+		arg.ID,
+	)
+	return err
+}
+
+const updateEvent = `-- name: UpdateEvent :exec
+UPDATE event
+SET window_title = ?, duration = ?, is_open = ?
+WHERE id = ?
+`
+
+type UpdateEventParams struct {
+	WindowTitle sql.NullString
+	Duration    int64
+	IsOpen      int64
+	ID          int64
+}
+
+func (q *Queries) UpdateEvent(ctx context.Context, arg UpdateEventParams) error {
+	_, err := q.db.ExecContext(ctx, updateEvent,
+		arg.WindowTitle,
+		arg.Duration,
+		arg.IsOpen,
+		arg.ID,
+	)
+	return err
+}
+
+const upsertPassword = `-- name: UpsertPassword :exec
+INSERT INTO password (id, hash, updated_at)
+VALUES (1, ?, ?)
+ON CONFLICT (id) DO UPDATE SET hash = excluded.hash, updated_at = excluded.updated_at
+`
+
+type UpsertPasswordParams struct {
+	Hash      string
+	UpdatedAt int64
+}
+
+func (q *Queries) UpsertPassword(ctx context.Context, arg UpsertPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, upsertPassword, arg.Hash, arg.UpdatedAt)
+	return err
+}
+
+const upsertSetting = `-- name: UpsertSetting :exec
+INSERT INTO setting (name, value, updated_at)
+VALUES (?, ?, ?)
+ON CONFLICT (name) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+`
+
+type UpsertSettingParams struct {
+	Name      string
+	Value     string
+	UpdatedAt int64
+}
+
+func (q *Queries) UpsertSetting(ctx context.Context, arg UpsertSettingParams) error {
+	_, err := q.db.ExecContext(ctx, upsertSetting, arg.Name, arg.Value, arg.UpdatedAt)
+	return err
+}
+
+const upsertSyncCursor = `-- name: UpsertSyncCursor :exec
+INSERT INTO sync_cursor (server_url, last_seq, updated_at)
+VALUES (?, ?, ?)
+ON CONFLICT (server_url) DO UPDATE SET last_seq = excluded.last_seq, updated_at = excluded.updated_at
+`
+
+type UpsertSyncCursorParams struct {
+	ServerUrl string
+	LastSeq   int64
+	UpdatedAt int64
+}
+
+func (q *Queries) UpsertSyncCursor(ctx context.Context, arg UpsertSyncCursorParams) error {
+	_, err := q.db.ExecContext(ctx, upsertSyncCursor, arg.ServerUrl, arg.LastSeq, arg.UpdatedAt)
+	return err
+}
+
+const upsertTeamReport = `-- name: UpsertTeamReport :exec
+INSERT INTO team_report (public_key, period_start, period_end, generated_at, received_at, document)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (public_key, period_start) DO UPDATE
+SET period_end = excluded.period_end, generated_at = excluded.generated_at, received_at = excluded.received_at, document = excluded.document
+WHERE excluded.generated_at >= team_report.generated_at
+`
+
+type UpsertTeamReportParams struct {
+	PublicKey   string
+	PeriodStart string
+	PeriodEnd   string
+	GeneratedAt int64
+	ReceivedAt  int64
+	Document    string
+}
+
+func (q *Queries) UpsertTeamReport(ctx context.Context, arg UpsertTeamReportParams) error {
+	_, err := q.db.ExecContext(ctx, upsertTeamReport,
+		arg.PublicKey,
+		arg.PeriodStart,
+		arg.PeriodEnd,
+		arg.GeneratedAt,
+		arg.ReceivedAt,
+		arg.Document,
 	)
 	return err
 }
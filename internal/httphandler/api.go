@@ -0,0 +1,193 @@
+package httphandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/services/activity"
+)
+
+// maxAPIBodyBytes limits the size of the JSON bodies accepted by the API.
+const maxAPIBodyBytes = 1 << 20
+
+type browserEventRequest struct {
+	// Timestamp is in milliseconds since the Unix epoch, which is what
+	// Date.now() returns. The time of the request is used if it's missing.
+	Timestamp int64  `json:"timestamp"`
+	Browser   string `json:"browser"`
+	URL       string `json:"url"`
+	Domain    string `json:"domain"`
+	Title     string `json:"title"`
+	Audible   bool   `json:"audible"`
+	Incognito bool   `json:"incognito"`
+}
+
+// BrowserEventsPost receives active tab changes from the browser extension.
+func (h *Handler) BrowserEventsPost(w http.ResponseWriter, r *http.Request) {
+	var req browserEventRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.URL == "" && req.Domain == "" && !req.Incognito {
+		writeJSONError(w, http.StatusBadRequest, "either url or domain is required")
+		return
+	}
+
+	event := activity.TabEvent{
+		Browser:   req.Browser,
+		URL:       req.URL,
+		Domain:    req.Domain,
+		Title:     req.Title,
+		Audible:   req.Audible,
+		Incognito: req.Incognito,
+	}
+	if req.Timestamp != 0 {
+		event.Timestamp = time.UnixMilli(req.Timestamp)
+	}
+
+	if err := activity.SaveTabEvent(r.Context(), h.Queries, h.Config(), event); err != nil {
+		h.renderInternalServerError(w, r, fmt.Errorf("saving the browser event: %v", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// heartbeatRequest uses the field names of WakaTime heartbeats.
+type heartbeatRequest struct {
+	// Time is in seconds since the Unix epoch and may have a fractional part.
+	Time     float64 `json:"time"`
+	Entity   string  `json:"entity"`
+	Project  string  `json:"project"`
+	Language string  `json:"language"`
+	Branch   string  `json:"branch"`
+	Editor   string  `json:"editor"`
+	Plugin   string  `json:"plugin"`
+	IsWrite  bool    `json:"is_write"`
+}
+
+// HeartbeatsPost receives heartbeats from editor plugins. The body can be a
+// single heartbeat or an array of them.
+func (h *Handler) HeartbeatsPost(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	if err := decodeJSON(w, r, &raw); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var reqs []heartbeatRequest
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(raw, &reqs); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else {
+		var req heartbeatRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		reqs = append(reqs, req)
+	}
+
+	for _, req := range reqs {
+		if req.Entity == "" {
+			writeJSONError(w, http.StatusBadRequest, "entity is required")
+			return
+		}
+	}
+
+	for _, req := range reqs {
+		heartbeat := activity.Heartbeat{
+			Entity:   req.Entity,
+			Project:  req.Project,
+			Language: req.Language,
+			Branch:   req.Branch,
+			Editor:   req.Editor,
+			IsWrite:  req.IsWrite,
+		}
+		if heartbeat.Editor == "" {
+			heartbeat.Editor = req.Plugin
+		}
+		if req.Time != 0 {
+			heartbeat.Time = time.UnixMilli(int64(req.Time * 1000))
+		}
+
+		if err := activity.SaveHeartbeat(r.Context(), h.Queries, heartbeat); err != nil {
+			h.renderInternalServerError(w, r, fmt.Errorf("saving the heartbeat: %v", err))
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]int{"saved": len(reqs)})
+}
+
+type shellEventRequest struct {
+	// Start and End are in milliseconds since the Unix epoch. The time of the
+	// request is used for End if it's missing.
+	Start      int64  `json:"start"`
+	End        int64  `json:"end"`
+	Command    string `json:"command"`
+	Cwd        string `json:"cwd"`
+	ExitStatus *int   `json:"exit_status"`
+	Shell      string `json:"shell"`
+}
+
+// ShellEventsPost receives the commands reported by the shell hooks installed
+// with `telltime hook init`.
+func (h *Handler) ShellEventsPost(w http.ResponseWriter, r *http.Request) {
+	var req shellEventRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if strings.TrimSpace(req.Command) == "" {
+		writeJSONError(w, http.StatusBadRequest, "command is required")
+		return
+	}
+
+	event := activity.ShellEvent{
+		Command:    req.Command,
+		Cwd:        req.Cwd,
+		ExitStatus: req.ExitStatus,
+		Shell:      req.Shell,
+	}
+	if req.Start != 0 {
+		event.Start = time.UnixMilli(req.Start)
+	}
+	if req.End != 0 {
+		event.End = time.UnixMilli(req.End)
+	}
+
+	if err := activity.SaveShellEvent(r.Context(), h.Queries, h.Config(), event); err != nil {
+		h.renderInternalServerError(w, r, fmt.Errorf("saving the shell event: %v", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodyBytes)).Decode(dst)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		slog.Error("failed to write the JSON response", "err", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,196 @@
+package httphandler
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/services/auth"
+	"github.com/bnuredini/telltime/internal/templates"
+)
+
+// publicPaths can be reached without logging in. /healthz reveals nothing
+// about the activity and is meant for monitoring.
+var publicPaths = []string{"/login", "/healthz"}
+
+// RequireAuth makes every request outside publicPaths and /static/ send
+// either the API token or a session cookie. Pages answer missing credentials
+// by redirecting to the login form and everything else with a 401.
+//
+// While authentication is disabled, the server only listens on the loopback
+// address, and only requests for a loopback host are let through. Otherwise a
+// site whose domain resolves to 127.0.0.1 (DNS rebinding) would be same-origin
+// with the dashboard and could read it.
+func (h *Handler) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.AuthEnabled() {
+			if !h.allowedHost(r.Host) {
+				h.renderError(w, r, http.StatusForbidden, "unexpected host")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if isPublicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if token, ok := bearerToken(r); ok {
+			if !auth.TokensEqual(token, h.APIToken) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="telltime", error="invalid_token"`)
+				h.renderError(w, r, http.StatusUnauthorized, "invalid API token")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if cookie, err := r.Cookie(auth.SessionCookie); err == nil {
+			valid, err := auth.ValidSession(r.Context(), h.Queries, cookie.Value, time.Now())
+			if err != nil {
+				h.renderInternalServerError(w, r, err)
+				return
+			}
+			if valid {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if acceptsHTML(r) && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", `Bearer realm="telltime"`)
+		h.renderError(w, r, http.StatusUnauthorized, "authentication required")
+	})
+}
+
+// AuthEnabled reports whether a password was set when the server started.
+func (h *Handler) AuthEnabled() bool {
+	return h.APIToken != ""
+}
+
+func (h *Handler) LoginGet(w http.ResponseWriter, r *http.Request) {
+	if !h.AuthEnabled() {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	h.renderLogin(w, r, http.StatusOK, &templates.LoginForm{Next: safeRedirect(r.URL.Query().Get("next"))})
+}
+
+func (h *Handler) LoginPost(w http.ResponseWriter, r *http.Request) {
+	if !h.AuthEnabled() {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	form := &templates.LoginForm{Next: safeRedirect(r.PostForm.Get("next"))}
+
+	token, err := auth.Login(r.Context(), h.Queries, r.PostForm.Get("password"), time.Now())
+	if errors.Is(err, auth.ErrWrongPassword) {
+		form.Error = "The password is wrong."
+		h.renderLogin(w, r, http.StatusUnauthorized, form)
+		return
+	} else if err != nil {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(auth.SessionDuration.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, form.Next, http.StatusSeeOther)
+}
+
+func (h *Handler) LogoutPost(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(auth.SessionCookie); err == nil {
+		if err := auth.Logout(r.Context(), h.Queries, cookie.Value); err != nil {
+			h.renderInternalServerError(w, r, err)
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (h *Handler) renderLogin(w http.ResponseWriter, r *http.Request, status int, form *templates.LoginForm) {
+	tmplData := templates.NewData()
+	tmplData.LoginForm = form
+
+	w.WriteHeader(status)
+	err := templates.RenderPage(h.TemplateManager, w, templates.PageLogin, tmplData)
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
+	}
+}
+
+// allowedHost reports whether host, which may include a port, names this
+// machine's loopback interface or the address the server listens on.
+func (h *Handler) allowedHost(host string) bool {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	if bindAddress := h.Config().BindAddress; bindAddress != "" && strings.EqualFold(host, bindAddress) {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func isPublicPath(path string) bool {
+	return slices.Contains(publicPaths, path) || strings.HasPrefix(path, "/static/")
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+// safeRedirect only allows redirects to paths on this server so that the
+// login form can't be used to send the user elsewhere.
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, `/\`) {
+		return "/"
+	}
+
+	return next
+}
@@ -0,0 +1,242 @@
+package httphandler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/auth"
+	"github.com/bnuredini/telltime/internal/templates"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+const testPassword = "correct horse"
+
+// newAuthTestServer returns a server that requires a login the way the real
+// one does, with a page at / and an API endpoint at /api/things.
+func newAuthTestServer(t *testing.T) (*Handler, http.Handler) {
+	t.Helper()
+
+	templateManager, err := templates.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := testutil.OpenDB(t)
+	if err = auth.SetPassword(context.Background(), db, testPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	h := New(db, dbgen.New(db), &conf.Config{}, templateManager)
+	h.APIToken = auth.NewToken()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /api/things", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /api/things", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /login", h.LoginGet)
+	mux.HandleFunc("POST /login", h.LoginPost)
+
+	return h, RequestID(h.RejectCrossOrigin(h.RequireAuth(h.WithErrorPages(mux))))
+}
+
+func TestRequireAuth(t *testing.T) {
+	h, handler := newAuthTestServer(t)
+
+	session, err := auth.Login(context.Background(), h.Queries, testPassword, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		accept       string
+		token        string
+		session      string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"page without credentials", "/?date=2025-06-02", "text/html", "", "", http.StatusSeeOther, "/login?next=%2F%3Fdate%3D2025-06-02"},
+		{"API without credentials", "/api/things", "", "", "", http.StatusUnauthorized, ""},
+		{"API token", "/api/things", "", h.APIToken, "", http.StatusOK, ""},
+		{"wrong API token", "/api/things", "", "nope", "", http.StatusUnauthorized, ""},
+		{"session", "/", "text/html", "", session, http.StatusOK, ""},
+		{"unknown session", "/api/things", "", "", "nope", http.StatusUnauthorized, ""},
+		{"public path", "/healthz", "", "", "", http.StatusOK, ""},
+		{"login page", "/login", "text/html", "", "", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		if tt.session != "" {
+			req.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: tt.session})
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.wantStatus)
+		}
+		if got := rec.Header().Get("Location"); got != tt.wantLocation {
+			t.Errorf("%s: got Location %q, want %q", tt.name, got, tt.wantLocation)
+		}
+		if tt.wantStatus == http.StatusUnauthorized && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer") {
+			t.Errorf("%s: got WWW-Authenticate %q, want a Bearer challenge", tt.name, rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestRejectCrossOrigin(t *testing.T) {
+	h, handler := newAuthTestServer(t)
+
+	tests := []struct {
+		name          string
+		contentType   string
+		origin        string
+		secFetchSite  string
+		wantForbidden bool
+	}{
+		{"cross-site form", "application/x-www-form-urlencoded", "https://evil.example", "", true},
+		{"cross-site fetch metadata", "application/x-www-form-urlencoded", "", "cross-site", true},
+		{"same-site subdomain", "text/plain", "", "same-site", true},
+		{"same origin", "application/x-www-form-urlencoded", "http://example.com", "same-origin", false},
+		{"same host", "application/x-www-form-urlencoded", "http://example.com", "", false},
+		{"not a browser", "application/x-www-form-urlencoded", "", "", false},
+		// Browsers can't send JSON cross-origin without a preflight.
+		{"JSON", "application/json", "https://evil.example", "", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/things", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", tt.contentType)
+		req.Header.Set("Authorization", "Bearer "+h.APIToken)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.secFetchSite != "" {
+			req.Header.Set("Sec-Fetch-Site", tt.secFetchSite)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Code == http.StatusForbidden; got != tt.wantForbidden {
+			t.Errorf("%s: got status %d, want forbidden=%v", tt.name, rec.Code, tt.wantForbidden)
+		}
+	}
+}
+
+func TestRejectUnexpectedHost(t *testing.T) {
+	templateManager, err := templates.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := testutil.OpenDB(t)
+
+	// Without a password, the server only listens on the loopback address.
+	h := New(db, dbgen.New(db), &conf.Config{BindAddress: "127.0.0.2"}, templateManager)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /api/things", func(w http.ResponseWriter, r *http.Request) {})
+	handler := RequestID(h.RejectCrossOrigin(h.RequireAuth(h.WithErrorPages(mux))))
+
+	tests := []struct {
+		host          string
+		wantForbidden bool
+	}{
+		{"localhost:8000", false},
+		{"LOCALHOST", false},
+		{"127.0.0.1:8000", false},
+		{"[::1]:8000", false},
+		{"127.0.0.2:8000", false},
+		// A domain that was rebound to the loopback address.
+		{"evil.example:8000", true},
+		{"localhost.evil.example:8000", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		for _, req := range []*http.Request{
+			httptest.NewRequest(http.MethodGet, "/", nil),
+			httptest.NewRequest(http.MethodPost, "/api/things", strings.NewReader("{}")),
+		} {
+			req.Host = tt.host
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Code == http.StatusForbidden; got != tt.wantForbidden {
+				t.Errorf("%s %s with host %q: got status %d, want forbidden=%v", req.Method, req.URL.Path, tt.host, rec.Code, tt.wantForbidden)
+			}
+		}
+	}
+}
+
+func TestLoginPost(t *testing.T) {
+	_, handler := newAuthTestServer(t)
+
+	login := func(password string, next string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		form := url.Values{"password": {password}, "next": {next}}
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "text/html")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := login("wrong horse", "/activity")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: got status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("wrong password: got a session cookie")
+	}
+	if !strings.Contains(rec.Body.String(), "The password is wrong.") {
+		t.Error("wrong password: the login form doesn't show the error")
+	}
+
+	tests := []struct {
+		next         string
+		wantLocation string
+	}{
+		{"/activity?date=2025-06-02", "/activity?date=2025-06-02"},
+		{"https://evil.example/", "/"},
+		{"//evil.example/", "/"},
+		{`/\evil.example/`, "/"},
+		{"", "/"},
+	}
+
+	for _, tt := range tests {
+		rec := login(testPassword, tt.next)
+		if rec.Code != http.StatusSeeOther {
+			t.Errorf("next=%q: got status %d, want %d", tt.next, rec.Code, http.StatusSeeOther)
+		}
+		if got := rec.Header().Get("Location"); got != tt.wantLocation {
+			t.Errorf("next=%q: got Location %q, want %q", tt.next, got, tt.wantLocation)
+		}
+
+		var session *http.Cookie
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == auth.SessionCookie {
+				session = c
+			}
+		}
+		if session == nil || session.Value == "" || !session.HttpOnly {
+			t.Errorf("next=%q: got cookie %v, want an HttpOnly session cookie", tt.next, session)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package httphandler
+
+import (
+	"net/http"
+
+	"github.com/bnuredini/telltime/internal/templates"
+)
+
+// DocsGet serves the documentation. The index is shown if no name is given.
+func (h *Handler) DocsGet(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if name == "" {
+		name = templates.DocIndex
+	}
+
+	if h.TemplateManager.Docs[name] == nil {
+		h.renderNotFound(w, r)
+		return
+	}
+
+	err := templates.RenderDoc(h.TemplateManager, w, name, templates.NewData())
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,126 @@
+package httphandler
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/bnuredini/telltime/internal/templates"
+)
+
+var errorPages = map[int]templates.PageName{
+	http.StatusNotFound:            templates.Page404,
+	http.StatusMethodNotAllowed:    templates.Page405,
+	http.StatusInternalServerError: templates.Page500,
+}
+
+// WithErrorPages answers requests that don't match any of the patterns in mux
+// with the error pages instead of the mux's plain text responses.
+func (h *Handler) WithErrorPages(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// Without a pattern, the mux's handler responds with 404 or, if the
+		// path matches for other methods, with 405 and the allowed methods.
+		rec := &statusRecorder{header: make(http.Header)}
+		handler.ServeHTTP(rec, r)
+
+		if rec.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+			h.renderError(w, r, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+			return
+		}
+		h.renderNotFound(w, r)
+	})
+}
+
+// renderError responds with the error page for status, or with a JSON error
+// if the client doesn't accept HTML. Both include the request ID, which is
+// also logged with the error so that the two can be matched up.
+func (h *Handler) renderError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	requestID := requestIDFrom(r.Context())
+
+	if !acceptsHTML(r) {
+		writeJSON(w, status, map[string]string{
+			"error":      message,
+			"request_id": requestID,
+		})
+		return
+	}
+
+	page, ok := errorPages[status]
+	if !ok {
+		page = templates.PageError
+	}
+
+	tmplData := templates.NewData()
+	tmplData.Error = &templates.ErrorInfo{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    message,
+		RequestID:  requestID,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+	}
+
+	w.WriteHeader(status)
+	err := templates.RenderPage(h.TemplateManager, w, page, tmplData)
+	if err != nil {
+		slog.Error("rendering failed", "err", err, "request_id", requestID)
+	}
+}
+
+// renderInternalServerError logs err and responds with a 500. The details of
+// err are only logged, not shown to the client.
+func (h *Handler) renderInternalServerError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.Error(
+		"failed to handle the request",
+		"err", err,
+		"method", r.Method,
+		"path", r.URL.Path,
+		"request_id", requestIDFrom(r.Context()),
+		"stack", string(debug.Stack()),
+	)
+
+	h.renderError(w, r, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+}
+
+func (h *Handler) renderNotFound(w http.ResponseWriter, r *http.Request) {
+	h.renderError(w, r, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+}
+
+// acceptsHTML reports whether the client asked for HTML. Browsers and htmx
+// always do, while API clients usually send "*/*" or nothing at all.
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html") || r.Header.Get("HX-Request") == "true"
+}
+
+// statusRecorder captures the status and the headers written by a handler
+// and discards the body.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (rec *statusRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	return len(b), nil
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
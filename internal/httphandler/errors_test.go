@@ -0,0 +1,78 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/templates"
+)
+
+func TestWithErrorPages(t *testing.T) {
+	templateManager, err := templates.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(nil, nil, &conf.Config{}, templateManager)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /api/things", func(w http.ResponseWriter, r *http.Request) {})
+	handler := RequestID(h.WithErrorPages(mux))
+
+	tests := []struct {
+		method     string
+		path       string
+		accept     string
+		wantStatus int
+		wantAllow  string
+		wantHTML   bool
+	}{
+		{http.MethodGet, "/", "text/html", http.StatusOK, "", false},
+		{http.MethodGet, "/missing", "text/html,application/xhtml+xml", http.StatusNotFound, "", true},
+		{http.MethodGet, "/missing", "*/*", http.StatusNotFound, "", false},
+		{http.MethodGet, "/api/things", "", http.StatusMethodNotAllowed, "POST", false},
+		{http.MethodDelete, "/", "text/html", http.StatusMethodNotAllowed, "GET, HEAD", true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		name := tt.method + " " + tt.path
+		if got := rec.Code; got != tt.wantStatus {
+			t.Errorf("%s: got status %d, want %d", name, got, tt.wantStatus)
+		}
+		if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+			t.Errorf("%s: got Allow %q, want %q", name, got, tt.wantAllow)
+		}
+
+		requestID := rec.Header().Get("X-Request-ID")
+		if requestID == "" {
+			t.Errorf("%s: missing the request ID", name)
+		}
+		if tt.wantStatus == http.StatusOK {
+			continue
+		}
+
+		body := rec.Body.String()
+		if tt.wantHTML {
+			if !strings.Contains(body, "<html") || !strings.Contains(body, requestID) {
+				t.Errorf("%s: got %q, want an HTML page with the request ID", name, body)
+			}
+			continue
+		}
+
+		var resp map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Errorf("%s: got %q, want JSON: %v", name, body, err)
+		} else if resp["request_id"] != requestID || resp["error"] == "" {
+			t.Errorf("%s: got %v, want an error with the request ID %q", name, resp, requestID)
+		}
+	}
+}
@@ -1,17 +1,18 @@
 package httphandler
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
-	"log"
-	"log/slog"
+	"errors"
+	"fmt"
 	"net/http"
-	"runtime/debug"
 	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/bnuredini/telltime/internal/conf"
 	"github.com/bnuredini/telltime/internal/dbgen"
 	"github.com/bnuredini/telltime/internal/services/activity"
 	"github.com/bnuredini/telltime/internal/templates"
@@ -21,14 +22,35 @@ type Handler struct {
 	DB              *sql.DB
 	Queries         *dbgen.Queries
 	TemplateManager *templates.Manager
+
+	// APIToken is what API clients have to send once a password is set. It's
+	// empty while authentication is disabled.
+	APIToken string
+
+	// config is replaced as a whole when the settings change instead of
+	// being modified in place, so it can be read without locking.
+	config atomic.Pointer[conf.Config]
 }
 
-func New(db *sql.DB, queries *dbgen.Queries, templateManager *templates.Manager) *Handler {
-	return &Handler{
+func New(
+	db *sql.DB,
+	queries *dbgen.Queries,
+	config *conf.Config,
+	templateManager *templates.Manager,
+) *Handler {
+	h := &Handler{
 		DB:              db,
 		Queries:         queries,
 		TemplateManager: templateManager,
 	}
+	h.config.Store(config)
+
+	return h
+}
+
+// Config returns the current configuration. It must not be modified.
+func (h *Handler) Config() *conf.Config {
+	return h.config.Load()
 }
 
 func (h *Handler) HomeGet(w http.ResponseWriter, r *http.Request) {
@@ -38,12 +60,14 @@ func (h *Handler) HomeGet(w http.ResponseWriter, r *http.Request) {
 		time.Now(),
 	)
 
-	if err := activity.Save(h.DB); err != nil {
-		slog.Error("serving home: failed to save activty data", "err", err)
-	}
-
-	start, end := activity.GetDayInterval()
-	programStats, err := activity.GetProgramStats(context.Background(), h.Queries, start, end)
+	start, end := activity.GetDayInterval(h.Config().DayStartHour)
+	programStats, err := activity.GetProgramStats(
+		r.Context(),
+		h.Queries,
+		start,
+		end,
+		activity.OptionsFromConfig(h.Config()),
+	)
 	if err != nil {
 		h.renderInternalServerError(w, r, err)
 		return
@@ -51,8 +75,30 @@ func (h *Handler) HomeGet(w http.ResponseWriter, r *http.Request) {
 
 	tmplData := templates.NewData()
 	tmplData.ProgramStats = programStats
+	tmplData.LiveStatus = newLiveStatus(programStats)
+	tmplData.CodingStats, err = activity.GetCodingStats(
+		r.Context(),
+		h.Queries,
+		start,
+		end,
+		activity.OptionsFromConfig(h.Config()),
+	)
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
+	tmplData.ShellStats, err = activity.GetShellStats(r.Context(), h.Queries, start, end)
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
 	tmplData.CalendarData = templates.NewCalendarData(currDate)
 	tmplData.SelectedDate = time.Now().Format("2006-01-02")
+	tmplData.GroupBy = string(activity.GroupByClass)
+	if err = h.setDevices(r.Context(), tmplData, ""); err != nil {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
 
 	err = templates.RenderPage(h.TemplateManager, w, templates.PageHome, tmplData)
 	if err != nil {
@@ -61,19 +107,33 @@ func (h *Handler) HomeGet(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) ActivityGet(w http.ResponseWriter, r *http.Request) {
-	if err := activity.Save(h.DB); err != nil {
-		slog.Error("serving page for activities: failed to save activty data", "err", err)
+	selectedDate := parseISO8601Date(r.URL.Query().Get("date"), time.Now())
+	start, end := activity.GetDayIntervalForDate(selectedDate, h.Config().DayStartHour)
+
+	opts := h.optionsFromRequest(r)
+	timeline, err := activity.GetTimeline(r.Context(), h.Queries, start, end, opts)
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
+		return
 	}
-	// TODO: Format these values for the frontend. The NullString values should
-	// be sent to the templates. Also consider adding template function instead.
-	events, err := h.Queries.GetEvents(context.Background())
+
+	edits, err := h.Queries.GetEventEdits(r.Context(), 50)
 	if err != nil {
 		h.renderInternalServerError(w, r, err)
 		return
 	}
 
 	tmplData := templates.NewData()
-	tmplData.WindowChangeEvents = events
+	tmplData.Timeline = timeline
+	tmplData.EventEdits = edits
+	tmplData.SelectedDate = selectedDate.Format("2006-01-02")
+	tmplData.PrevDate = selectedDate.AddDate(0, 0, -1).Format("2006-01-02")
+	tmplData.NextDate = selectedDate.AddDate(0, 0, 1).Format("2006-01-02")
+	tmplData.FormError = r.URL.Query().Get("error")
+	if err = h.setDevices(r.Context(), tmplData, opts.DeviceID); err != nil {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
 
 	err = templates.RenderPage(h.TemplateManager, w, templates.PageActivity, tmplData)
 	if err != nil {
@@ -81,6 +141,68 @@ func (h *Handler) ActivityGet(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ActivityEditsPost handles the forms on the activity page. Every kind of edit
+// is stored in the overlay; the recorded events are left as they are.
+func (h *Handler) ActivityEditsPost(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	redirectDate := r.PostForm.Get("date")
+	params, err := parseEditForm(r)
+	if err != nil {
+		redirectToActivity(w, r, redirectDate, err)
+		return
+	}
+
+	ctx := r.Context()
+	switch kind := r.PostForm.Get("kind"); kind {
+	case activity.EditKindAdd:
+		_, err = activity.AddManualEntry(ctx, h.Queries, params)
+	case activity.EditKindRelabel:
+		_, err = activity.Relabel(ctx, h.Queries, params)
+	case activity.EditKindSplit:
+		var at time.Time
+		at, err = parseFormTime(r.PostForm.Get("at"))
+		if err == nil {
+			_, err = activity.Split(ctx, h.Queries, at, params)
+		}
+	case activity.EditKindDelete:
+		_, err = activity.DeleteRange(ctx, h.Queries, params)
+	default:
+		err = fmt.Errorf("%w: unknown kind %q", activity.ErrInvalidEdit, kind)
+	}
+
+	if err != nil && !errors.Is(err, activity.ErrInvalidEdit) {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
+
+	redirectToActivity(w, r, redirectDate, err)
+}
+
+func (h *Handler) ActivityEditRevertPost(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PostForm.Get("id"), 10, 64)
+	if err != nil {
+		redirectToActivity(w, r, r.PostForm.Get("date"), fmt.Errorf("%w: bad edit ID", activity.ErrInvalidEdit))
+		return
+	}
+
+	err = activity.RevertEdit(r.Context(), h.Queries, id)
+	if err != nil && !errors.Is(err, activity.ErrInvalidEdit) {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
+
+	redirectToActivity(w, r, r.PostForm.Get("date"), err)
+}
+
 func (h *Handler) CalendarSelectGet(w http.ResponseWriter, r *http.Request) {
 	selectedDate := parseISO8601Date(r.URL.Query().Get("date"), time.Now())
 
@@ -90,7 +212,9 @@ func (h *Handler) CalendarSelectGet(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 	b, _ := json.Marshal(payload)
-	w.Header().Set("HX-Trigger", string(b))
+	// The panels read the date from the new calendar, so they're only told
+	// about it once it's been swapped in.
+	w.Header().Set("HX-Trigger-After-Swap", string(b))
 
 	tmplData := templates.NewCalendarData(selectedDate)
 	err := templates.RenderPartial(h.TemplateManager, w, "calendar", tmplData)
@@ -112,7 +236,12 @@ func (h *Handler) MostUsedProgramsGet(w http.ResponseWriter, r *http.Request) {
 		orderDirection = "desc"
 	}
 
-	programStats, err := activity.GetProgramStatsForDate(context.Background(), h.Queries, selectedDate)
+	opts := h.optionsFromRequest(r)
+	if activity.ProgramGrouping(r.URL.Query().Get("group-by")) == activity.GroupByExecutable {
+		opts.GroupBy = activity.GroupByExecutable
+	}
+
+	programStats, err := activity.GetProgramStatsForDate(r.Context(), h.Queries, selectedDate, opts)
 	if err != nil {
 		h.renderInternalServerError(w, r, err)
 		return
@@ -145,6 +274,11 @@ func (h *Handler) MostUsedProgramsGet(w http.ResponseWriter, r *http.Request) {
 	tmplData.SelectedDate = r.URL.Query().Get("date")
 	tmplData.OrderBy = orderBy
 	tmplData.OrderDirection = orderDirection
+	tmplData.GroupBy = string(opts.GroupBy)
+	if err = h.setDevices(r.Context(), tmplData, opts.DeviceID); err != nil {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
 
 	err = templates.RenderPartial(h.TemplateManager, w, "most-used-programs", tmplData)
 	if err != nil {
@@ -152,13 +286,42 @@ func (h *Handler) MostUsedProgramsGet(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *Handler) renderInternalServerError(w http.ResponseWriter, r *http.Request, err error) {
-	log.Printf("%s\n%s\n", err.Error(), debug.Stack())
+func (h *Handler) CodingStatsGet(w http.ResponseWriter, r *http.Request) {
+	selectedDate := parseISO8601Date(r.URL.Query().Get("date"), time.Now())
+	start, end := activity.GetDayIntervalForDate(selectedDate, h.Config().DayStartHour)
 
-	w.WriteHeader(http.StatusInternalServerError)
-	// TODO: Pass the Request struct value to the template.
-	err = templates.RenderPage(h.TemplateManager, w, templates.Page500, templates.NewData())
+	codingStats, err := activity.GetCodingStats(r.Context(), h.Queries, start, end, h.optionsFromRequest(r))
 	if err != nil {
-		slog.Error("rendering failed", "err", err)
+		h.renderInternalServerError(w, r, err)
+		return
+	}
+
+	tmplData := templates.NewData()
+	tmplData.CodingStats = codingStats
+	tmplData.SelectedDate = selectedDate.Format("2006-01-02")
+
+	err = templates.RenderPartial(h.TemplateManager, w, "coding-stats", tmplData)
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
+	}
+}
+
+func (h *Handler) ShellStatsGet(w http.ResponseWriter, r *http.Request) {
+	selectedDate := parseISO8601Date(r.URL.Query().Get("date"), time.Now())
+	start, end := activity.GetDayIntervalForDate(selectedDate, h.Config().DayStartHour)
+
+	shellStats, err := activity.GetShellStats(r.Context(), h.Queries, start, end)
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
+
+	tmplData := templates.NewData()
+	tmplData.ShellStats = shellStats
+	tmplData.SelectedDate = selectedDate.Format("2006-01-02")
+
+	err = templates.RenderPartial(h.TemplateManager, w, "shell-stats", tmplData)
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
 	}
 }
@@ -0,0 +1,72 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/templates"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+// hxValsPattern matches the hx-vals attributes, which are always quoted with
+// single quotes.
+var hxValsPattern = regexp.MustCompile(`hx-vals='([^']*)'`)
+
+// TestDashboardWorksWithoutEval makes sure that the dashboard doesn't rely on
+// htmx evaluating JavaScript, which the content security policy forbids.
+func TestDashboardWorksWithoutEval(t *testing.T) {
+	templateManager, err := templates.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := testutil.OpenDB(t)
+	h := New(db, dbgen.New(db), &conf.Config{}, templateManager)
+
+	pages := map[string]http.HandlerFunc{
+		"/":                                   h.HomeGet,
+		"/calendar/select?date=2025-06-02":    h.CalendarSelectGet,
+		"/most-used-programs?date=2025-06-02": h.MostUsedProgramsGet,
+	}
+
+	for path, handler := range pages {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d, want %d", path, rec.Code, http.StatusOK)
+		}
+
+		body := rec.Body.String()
+		if strings.Contains(body, "js:") {
+			t.Errorf("%s: found values that htmx would have to evaluate", path)
+		}
+
+		matches := hxValsPattern.FindAllStringSubmatch(body, -1)
+		if len(matches) == 0 {
+			t.Errorf("%s: found no hx-vals", path)
+		}
+		for _, m := range matches {
+			var vals map[string]any
+			if err := json.Unmarshal([]byte(html.UnescapeString(m[1])), &vals); err != nil {
+				t.Errorf("%s: hx-vals %q isn't JSON: %v", path, m[1], err)
+			}
+		}
+	}
+
+	// The panels read the selected date from the calendar once it's swapped
+	// in.
+	rec := httptest.NewRecorder()
+	h.CalendarSelectGet(rec, httptest.NewRequest(http.MethodGet, "/calendar/select?date=2025-06-02", nil))
+	if got := rec.Header().Get("HX-Trigger-After-Swap"); !strings.Contains(got, `"selected-date"`) {
+		t.Errorf("got HX-Trigger-After-Swap %q, want the selected-date event", got)
+	}
+	if !strings.Contains(rec.Body.String(), `id="selected-date" name="date" value="2025-06-02"`) {
+		t.Error("the calendar doesn't hold the selected date")
+	}
+}
@@ -0,0 +1,115 @@
+package httphandler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/templates"
+)
+
+// defaultHeatmapRangeDays is how many days the hour heatmap covers unless a
+// range is selected.
+const defaultHeatmapRangeDays = 28
+
+// HeatmapGet shows when time was spent: on the days of the year in the year
+// query parameter and in the hours of the weekdays between start and end. Both
+// can be limited to a program or a category.
+func (h *Handler) HeatmapGet(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+	now := time.Now()
+	dayStartHour := h.Config().DayStartHour
+
+	year, err := strconv.Atoi(query.Get("year"))
+	if err != nil || year < 1970 || year > 9999 {
+		year = now.Year()
+	}
+
+	rangeEnd := parseISO8601Date(query.Get("end"), now)
+	rangeStart := parseISO8601Date(query.Get("start"), rangeEnd.AddDate(0, 0, 1-defaultHeatmapRangeDays))
+	if rangeStart.After(rangeEnd) {
+		rangeStart, rangeEnd = rangeEnd, rangeStart
+	}
+
+	filter := activity.HeatmapFilter{
+		Program:  query.Get("program"),
+		Category: query.Get("category"),
+	}
+	opts := h.optionsFromRequest(r)
+
+	yearStart, _ := activity.GetDayIntervalForDate(time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local), dayStartHour)
+	yearEnd, _ := activity.GetDayIntervalForDate(time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.Local), dayStartHour)
+	yearSegments, err := activity.GetTimeline(ctx, h.Queries, yearStart, yearEnd, opts)
+	if err != nil {
+		h.renderInternalServerError(w, r, fmt.Errorf("reading the year: %v", err))
+		return
+	}
+
+	start, _ := activity.GetDayIntervalForDate(time.Date(rangeStart.Year(), rangeStart.Month(), rangeStart.Day(), 0, 0, 0, 0, time.Local), dayStartHour)
+	_, end := activity.GetDayIntervalForDate(time.Date(rangeEnd.Year(), rangeEnd.Month(), rangeEnd.Day(), 0, 0, 0, 0, time.Local), dayStartHour)
+	rangeSegments, err := activity.GetTimeline(ctx, h.Queries, start, end, opts)
+	if err != nil {
+		h.renderInternalServerError(w, r, fmt.Errorf("reading the range: %v", err))
+		return
+	}
+
+	daily := activity.DailyDurations(activity.HourlyDurations(yearSegments, filter), dayStartHour)
+	grid := activity.WeekdayHourDurations(activity.HourlyDurations(rangeSegments, filter))
+
+	programs, categories := heatmapFilterOptions(yearSegments, rangeSegments)
+
+	tmplData := templates.NewData()
+	tmplData.Heatmap = &templates.HeatmapData{
+		Year:       templates.NewYearHeatmap(year, daily),
+		Hours:      templates.NewHourHeatmap(grid),
+		Program:    filter.Program,
+		Category:   filter.Category,
+		Programs:   programs,
+		Categories: categories,
+		RangeStart: rangeStart.Format("2006-01-02"),
+		RangeEnd:   rangeEnd.Format("2006-01-02"),
+	}
+	if err = h.setDevices(ctx, tmplData, opts.DeviceID); err != nil {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
+
+	err = templates.RenderPage(h.TemplateManager, w, templates.PageHeatmap, tmplData)
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
+	}
+}
+
+// heatmapFilterOptions lists the programs and the categories that time was
+// spent in, sorted by name.
+func heatmapFilterOptions(segmentLists ...[]*activity.Segment) (programs []string, categories []string) {
+	seenPrograms := make(map[string]bool)
+	seenCategories := make(map[string]bool)
+
+	for _, segments := range segmentLists {
+		for _, s := range segments {
+			category := s.Category
+			if category == "" {
+				category = activity.UncategorizedName
+			}
+
+			if !seenPrograms[s.Label] {
+				seenPrograms[s.Label] = true
+				programs = append(programs, s.Label)
+			}
+			if !seenCategories[category] {
+				seenCategories[category] = true
+				categories = append(categories, category)
+			}
+		}
+	}
+
+	sort.Strings(programs)
+	sort.Strings(categories)
+
+	return programs, categories
+}
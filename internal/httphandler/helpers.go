@@ -1,11 +1,42 @@
 package httphandler
 
 import (
-	"time"
+	"context"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"strconv"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/templates"
 )
 
+// optionsFromRequest returns the options for the stats. They're limited to the
+// device in the device query parameter if there is one.
+func (h *Handler) optionsFromRequest(r *http.Request) activity.Options {
+	opts := activity.OptionsFromConfig(h.Config())
+	opts.DeviceID = r.URL.Query().Get("device")
+
+	return opts
+}
+
+// setDevices adds the devices to data so that the pages can offer to filter
+// by them.
+func (h *Handler) setDevices(ctx context.Context, data *templates.Data, selected string) error {
+	devices, err := h.Queries.GetDevices(ctx)
+	if err != nil {
+		return fmt.Errorf("reading the devices: %v", err)
+	}
+
+	data.Devices = devices
+	data.DeviceNames = activity.DeviceNames(devices)
+	data.SelectedDevice = selected
+
+	return nil
+}
+
 func parseDate(rawTime string, rawTimeZone string, fallback time.Time) time.Time {
 	millis, err := strconv.ParseInt(rawTime, 10, 64)
 	if err != nil {
@@ -31,3 +62,65 @@ func parseISO8601Date(iso8601Date string, fallback time.Time) time.Time {
 
 	return t
 }
+
+// parseFormTime accepts the value of a datetime-local input or a Unix
+// timestamp (which is what the hidden fields on the activity page use).
+func parseFormTime(raw string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, raw, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %q is not a valid time", activity.ErrInvalidEdit, raw)
+}
+
+// parseEditForm reads the fields shared by every kind of edit. The end of the
+// range can be given either directly or as a duration such as "45m".
+func parseEditForm(r *http.Request) (activity.EditParams, error) {
+	params := activity.EditParams{
+		DeviceID: r.PostForm.Get("device"),
+		Label:    r.PostForm.Get("label"),
+		Category: r.PostForm.Get("category"),
+		Note:     r.PostForm.Get("note"),
+		Source:   activity.EditSourceWeb,
+	}
+
+	start, err := parseFormTime(r.PostForm.Get("start"))
+	if err != nil {
+		return params, err
+	}
+	params.Start = start
+
+	if rawDuration := r.PostForm.Get("duration"); rawDuration != "" {
+		duration, err := time.ParseDuration(rawDuration)
+		if err != nil {
+			return params, fmt.Errorf("%w: %q is not a valid duration", activity.ErrInvalidEdit, rawDuration)
+		}
+		params.End = start.Add(duration)
+	} else {
+		end, err := parseFormTime(r.PostForm.Get("end"))
+		if err != nil {
+			return params, err
+		}
+		params.End = end
+	}
+
+	return params, nil
+}
+
+func redirectToActivity(w http.ResponseWriter, r *http.Request, date string, err error) {
+	query := url.Values{}
+	if date != "" {
+		query.Set("date", date)
+	}
+	if err != nil {
+		query.Set("error", err.Error())
+	}
+
+	http.Redirect(w, r, "/activity?"+query.Encode(), http.StatusSeeOther)
+}
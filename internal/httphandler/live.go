@@ -0,0 +1,123 @@
+package httphandler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/templates"
+)
+
+const (
+	liveEventName = "live-status"
+
+	// liveRefreshInterval is how often the live status is pushed even if the
+	// window doesn't change so that the elapsed time keeps moving.
+	liveRefreshInterval = 15 * time.Second
+	liveTopPrograms     = 5
+)
+
+// LiveGet streams the current window and today's totals as server-sent
+// events. The home page subscribes to it through htmx's SSE extension.
+func (h *Handler) LiveGet(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming isn't supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	changes, unsubscribe := activity.Subscribe()
+	defer unsubscribe()
+
+	refreshTicker := time.NewTicker(liveRefreshInterval)
+	defer refreshTicker.Stop()
+
+	for {
+		if err := h.writeLiveStatus(r.Context(), w); err != nil {
+			slog.Error("streaming live status: failed to write", "err", err)
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changes:
+		case <-refreshTicker.C:
+		}
+	}
+}
+
+func (h *Handler) writeLiveStatus(ctx context.Context, w http.ResponseWriter) error {
+	start, end := activity.GetDayInterval(h.Config().DayStartHour)
+	programStats, err := activity.GetProgramStats(
+		ctx,
+		h.Queries,
+		start,
+		end,
+		activity.OptionsFromConfig(h.Config()),
+	)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	err = templates.RenderPartial(h.TemplateManager, buf, "live-status", newLiveStatus(programStats))
+	if err != nil {
+		return err
+	}
+
+	return writeSSE(w, liveEventName, buf.String())
+}
+
+func newLiveStatus(programStats []*activity.ProgramStat) *templates.LiveStatus {
+	status := &templates.LiveStatus{}
+
+	if curr, ok := activity.CurrentWindow(); ok {
+		status.WindowClass = curr.WindowClass
+		status.WindowName = curr.WindowName
+		status.ElapsedSecs = int64(time.Since(curr.StartTimestamp).Seconds())
+	}
+	status.OnDesktop = activity.OnDesktop()
+	status.Paused = activity.Paused()
+
+	sorted := make([]*activity.ProgramStat, len(programStats))
+	copy(sorted, programStats)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].DurationSecs > sorted[j].DurationSecs
+	})
+
+	for _, s := range sorted {
+		status.ScreenTimeSecs += s.DurationSecs
+	}
+	status.ProgramStats = sorted[:min(len(sorted), liveTopPrograms)]
+
+	return status
+}
+
+// writeSSE writes a single event. Every line of data needs its own "data:"
+// prefix, otherwise the client would cut the payload at the first newline.
+func writeSSE(w http.ResponseWriter, event string, data string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	_, err := w.Write([]byte(b.String()))
+
+	return err
+}
@@ -0,0 +1,85 @@
+package httphandler
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/templates"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+func TestLiveGet(t *testing.T) {
+	templateManager, err := templates.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := testutil.OpenDB(t)
+	h := New(db, dbgen.New(db), &conf.Config{}, templateManager)
+
+	srv := httptest.NewServer(http.HandlerFunc(h.LiveGet))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("got content type %q, want text/event-stream", got)
+	}
+
+	// The first frame is sent right away and ends with a blank line.
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && scanner.Text() != "" {
+		lines = append(lines, scanner.Text())
+	}
+	if err = scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lines) < 2 {
+		t.Fatalf("got %q, want an event with data", lines)
+	}
+	if want := "event: " + liveEventName; lines[0] != want {
+		t.Errorf("got %q, want %q", lines[0], want)
+	}
+
+	var data []string
+	for _, line := range lines[1:] {
+		payload, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			t.Fatalf("got line %q, want every line after the event name to be data", line)
+		}
+		data = append(data, payload)
+	}
+	if html := strings.Join(data, "\n"); !strings.Contains(html, "<") {
+		t.Errorf("got data %q, want the rendered live status", html)
+	}
+}
+
+func TestWriteSSESplitsLines(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := writeSSE(rec, "update", "<p>\n  hi\n</p>"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "event: update\ndata: <p>\ndata:   hi\ndata: </p>\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
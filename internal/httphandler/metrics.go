@@ -0,0 +1,90 @@
+package httphandler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/bnuredini/telltime/internal/services/activity"
+)
+
+// metricsContentType is the content type of version 0.0.4 of the Prometheus
+// text exposition format, which every Prometheus-compatible scraper accepts.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// MetricsGet exposes the state of the tracker and today's totals in the
+// Prometheus text format. The format is simple enough that it's written by
+// hand instead of pulling in the Prometheus client library. The totals can be
+// limited to one device with the device query parameter.
+func (h *Handler) MetricsGet(w http.ResponseWriter, r *http.Request) {
+	start, end := activity.GetDayInterval(h.Config().DayStartHour)
+	opts := h.optionsFromRequest(r)
+
+	programStats, err := activity.GetProgramStats(r.Context(), h.Queries, start, end, opts)
+	if err != nil {
+		h.renderInternalServerError(w, r, fmt.Errorf("getting program stats for the metrics: %v", err))
+		return
+	}
+
+	categoryStats, err := activity.GetCategoryStats(r.Context(), h.Queries, start, end, opts)
+	if err != nil {
+		h.renderInternalServerError(w, r, fmt.Errorf("getting category stats for the metrics: %v", err))
+		return
+	}
+
+	trackerStats := activity.GetTrackerStats()
+
+	buf := new(bytes.Buffer)
+
+	writeMetricHeader(buf, "telltime_save_duration_seconds", "summary", "Time spent writing the recorded events to the database.")
+	fmt.Fprintf(buf, "telltime_save_duration_seconds_sum %g\n", trackerStats.SaveSecondsSum)
+	fmt.Fprintf(buf, "telltime_save_duration_seconds_count %d\n", trackerStats.Saves)
+
+	writeMetricHeader(buf, "telltime_last_save_duration_seconds", "gauge", "Time spent on the last successful save.")
+	fmt.Fprintf(buf, "telltime_last_save_duration_seconds %g\n", trackerStats.LastSaveSecs)
+
+	writeMetricHeader(buf, "telltime_saves_failed_total", "counter", "Saves that failed to write the recorded events to the database.")
+	fmt.Fprintf(buf, "telltime_saves_failed_total %d\n", trackerStats.FailedSaves)
+
+	writeMetricHeader(buf, "telltime_tracker_errors_total", "counter", "Errors that occurred while reading the focused window.")
+	fmt.Fprintf(buf, "telltime_tracker_errors_total %d\n", trackerStats.TickErrors)
+
+	writeMetricHeader(buf, "telltime_pending_events", "gauge", "Recorded events that haven't been saved to the database yet.")
+	fmt.Fprintf(buf, "telltime_pending_events %d\n", trackerStats.PendingEvents)
+
+	sort.Slice(programStats, func(i, j int) bool {
+		return programStats[i].ProgramName < programStats[j].ProgramName
+	})
+	writeMetricHeader(buf, "telltime_program_seconds_today", "gauge", "Time spent in each program since the start of the day.")
+	for _, s := range programStats {
+		fmt.Fprintf(buf, "telltime_program_seconds_today{program=\"%s\"} %d\n", labelValueReplacer.Replace(s.ProgramName), s.DurationSecs)
+	}
+
+	sort.Slice(categoryStats, func(i, j int) bool {
+		return categoryStats[i].CategoryName < categoryStats[j].CategoryName
+	})
+	writeMetricHeader(buf, "telltime_category_seconds_today", "gauge", "Time spent in each category since the start of the day.")
+	for _, s := range categoryStats {
+		fmt.Fprintf(buf, "telltime_category_seconds_today{category=\"%s\"} %d\n", labelValueReplacer.Replace(s.CategoryName), s.DurationSecs)
+	}
+
+	writeMetricHeader(buf, "telltime_current_window_info", "gauge", "The class of the focused window. Missing if no window has been observed yet.")
+	if window, ok := activity.CurrentWindow(); ok {
+		fmt.Fprintf(buf, "telltime_current_window_info{class=\"%s\"} 1\n", labelValueReplacer.Replace(window.WindowClass))
+	}
+
+	w.Header().Set("Content-Type", metricsContentType)
+	if _, err = buf.WriteTo(w); err != nil {
+		slog.Error("failed to write the metrics", "err", err)
+	}
+}
+
+func writeMetricHeader(w io.Writer, name string, metricType string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
@@ -0,0 +1,81 @@
+package httphandler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+func TestMetricsGet(t *testing.T) {
+	db := testutil.OpenDB(t)
+	q := dbgen.New(db)
+	config := &conf.Config{
+		Categories: []conf.CategoryRule{{Category: `Work "deep"`, Classes: []string{`C:\Code`}}},
+	}
+	h := New(db, q, config, nil)
+
+	start, _ := activity.GetDayInterval(config.DayStartHour)
+	err := q.InsertEvents(context.Background(), dbgen.InsertEventsParams{
+		StartTime:   start.Unix(),
+		WindowClass: `C:\Code`,
+		Duration:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.MetricsGet(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Type"); got != metricsContentType {
+		t.Errorf("got content type %q, want %q", got, metricsContentType)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# HELP telltime_save_duration_seconds ",
+		"# TYPE telltime_save_duration_seconds summary\n",
+		"telltime_save_duration_seconds_count ",
+		"# TYPE telltime_saves_failed_total counter\n",
+		"# TYPE telltime_tracker_errors_total counter\n",
+		"# TYPE telltime_pending_events gauge\n",
+		"# TYPE telltime_program_seconds_today gauge\n",
+		`telltime_program_seconds_today{program="C:\\Code"} 1` + "\n",
+		"# TYPE telltime_category_seconds_today gauge\n",
+		`telltime_category_seconds_today{category="Work \"deep\""} 1` + "\n",
+		"# TYPE telltime_current_window_info gauge\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("the metrics don't contain %q:\n%s", want, body)
+		}
+	}
+
+	// Every metric has its help and type right before its samples.
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if strings.HasPrefix(line, "# ") {
+			continue
+		}
+		name, _, _ := strings.Cut(line, " ")
+		name, _, _ = strings.Cut(name, "{")
+		name = strings.TrimSuffix(strings.TrimSuffix(name, "_sum"), "_count")
+		if !strings.Contains(body, "# HELP "+name+" ") || !strings.Contains(body, "# TYPE "+name+" ") {
+			t.Errorf("%s has no HELP or TYPE line", name)
+		}
+	}
+}
+
+func TestLabelValueReplacer(t *testing.T) {
+	if got, want := labelValueReplacer.Replace("a\\b\"c\nd"), `a\\b\"c\nd`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
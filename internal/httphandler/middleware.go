@@ -0,0 +1,219 @@
+package httphandler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// contentSecurityPolicy allows htmx to be loaded from its CDN. Inline styles
+// are allowed since htmx adds the styles of its indicators to the page.
+// Scripts can't be evaluated, so hx-vals has to be plain JSON.
+const contentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' https://cdn.jsdelivr.net; " +
+	"style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data:; " +
+	"base-uri 'self'; " +
+	"form-action 'self'; " +
+	"frame-ancestors 'none'"
+
+// RequestID gives every request an ID, which is sent back in the X-Request-ID
+// header and shown on error pages. It's stored in the request's context, so
+// it's available to everything the handlers call with that context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := newRequestID()
+		w.Header().Set("X-Request-ID", id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// LogRequests logs every request once it's handled, together with its status
+// and how long it took. It has to run inside RequestID.
+func LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+
+		slog.LogAttrs(
+			r.Context(),
+			slog.LevelInfo,
+			"handled the request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rw.statusOrOK()),
+			slog.Int64("bytes", rw.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("request_id", requestIDFrom(r.Context())),
+		)
+	})
+}
+
+// RecoverPanics turns a panic in a handler into a 500 so that it's logged
+// with the request instead of only closing the connection. It has to run
+// inside LogRequests.
+func (h *Handler) RecoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// http.ErrAbortHandler is how handlers abort a response on
+			// purpose, and the server doesn't log it.
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			err := fmt.Errorf("panic: %v", recovered)
+			if rw, ok := w.(*responseWriter); ok && rw.status != 0 {
+				// It's too late for the error page, so the response is
+				// cut off instead.
+				slog.Error(
+					"failed to handle the request",
+					"err", err,
+					"method", r.Method,
+					"path", r.URL.Path,
+					"request_id", requestIDFrom(r.Context()),
+				)
+				panic(http.ErrAbortHandler)
+			}
+
+			h.renderInternalServerError(w, r, err)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SecurityHeaders sets the headers that stop browsers from sniffing content
+// types, from framing the pages and from running scripts from other origins.
+func SecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("Content-Security-Policy", contentSecurityPolicy)
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "same-origin")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RejectCrossOrigin protects against cross-site request forgery by rejecting
+// requests that change something and were sent by the browser on behalf of
+// another site. Browsers mark such requests with Sec-Fetch-Site or, if they're
+// older, with an Origin that doesn't match the host. Requests with a JSON body
+// are let through since browsers only send them cross-site after a CORS
+// preflight, which the server never allows, while browser extensions with
+// access to the server don't need one.
+func (h *Handler) RejectCrossOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if crossOrigin(r) {
+			h.renderError(w, r, http.StatusForbidden, "cross-origin request rejected")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func crossOrigin(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "application/json" {
+		return false
+	}
+
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return false
+	case "":
+	default:
+		return true
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// Not sent by a browser.
+		return false
+	}
+	u, err := url.Parse(origin)
+
+	return err != nil || u.Host != r.Host
+}
+
+// responseWriter records the status and the size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+
+	return n, err
+}
+
+// Flush is needed by the server-sent event streams, which check for
+// http.Flusher.
+func (rw *responseWriter) Flush() {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+func (rw *responseWriter) statusOrOK() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+
+	return rw.status
+}
@@ -0,0 +1,60 @@
+package httphandler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/templates"
+)
+
+func TestRecoverPanics(t *testing.T) {
+	templateManager, err := templates.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(nil, nil, &conf.Config{}, templateManager)
+
+	handler := RequestID(LogRequests(h.RecoverPanics(SecurityHeaders(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}),
+	))))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got, want := rec.Code, http.StatusInternalServerError; got != want {
+		t.Errorf("got status %d, want %d", got, want)
+	}
+	requestID := rec.Header().Get("X-Request-ID")
+	if body := rec.Body.String(); requestID == "" || !strings.Contains(body, requestID) {
+		t.Errorf("got %q, want the 500 page with the request ID %q", body, requestID)
+	}
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("got X-Content-Type-Options %q, want nosniff", got)
+	}
+}
+
+func TestLogRequestsKeepsFlusher(t *testing.T) {
+	var flushed bool
+	handler := LogRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("the writer doesn't implement http.Flusher")
+		}
+		flusher.Flush()
+		flushed = true
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/live", nil))
+
+	if !flushed || !rec.Flushed {
+		t.Errorf("got flushed=%v, recorder flushed=%v, want both", flushed, rec.Flushed)
+	}
+}
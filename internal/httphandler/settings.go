@@ -0,0 +1,136 @@
+package httphandler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/services/settings"
+	"github.com/bnuredini/telltime/internal/templates"
+)
+
+func (h *Handler) SettingsGet(w http.ResponseWriter, r *http.Request) {
+	form := newSettingsForm(settings.FromConfig(h.Config()))
+	form.Saved = r.URL.Query().Get("saved") != ""
+
+	h.renderSettings(w, r, http.StatusOK, form)
+}
+
+// SettingsPost validates and stores the settings and then applies them to the
+// server and the running tracker, so that they take effect without a restart.
+// Invalid input is shown back together with what's wrong with it.
+func (h *Handler) SettingsPost(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s, errs := parseSettingsForm(r)
+	if err := s.Validate(); err != nil {
+		var validationErr settings.ValidationError
+		if !errors.As(err, &validationErr) {
+			h.renderInternalServerError(w, r, err)
+			return
+		}
+
+		// Fields that couldn't be parsed keep the more specific error.
+		for name, problem := range validationErr {
+			if _, ok := errs[name]; !ok {
+				errs[name] = problem
+			}
+		}
+	}
+	if len(errs) > 0 {
+		form := newSettingsFormFromRequest(r)
+		form.Errors = errs
+		h.renderSettings(w, r, http.StatusUnprocessableEntity, form)
+		return
+	}
+
+	if err := settings.Save(r.Context(), h.DB, s); err != nil {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
+
+	config := *h.Config()
+	s.Apply(&config)
+	h.config.Store(&config)
+	activity.UpdateConfig(config)
+
+	http.Redirect(w, r, "/settings?saved=1", http.StatusSeeOther)
+}
+
+func (h *Handler) renderSettings(w http.ResponseWriter, r *http.Request, status int, form *templates.SettingsForm) {
+	form.AuthEnabled = h.AuthEnabled()
+
+	tmplData := templates.NewData()
+	tmplData.SettingsForm = form
+
+	w.WriteHeader(status)
+	err := templates.RenderPage(h.TemplateManager, w, templates.PageSettings, tmplData)
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
+	}
+}
+
+// parseSettingsForm reads the settings from the submitted form. Fields that
+// can't be parsed at all are reported separately since the returned settings
+// can't tell what was entered.
+func parseSettingsForm(r *http.Request) (settings.Settings, settings.ValidationError) {
+	var s settings.Settings
+	errs := make(settings.ValidationError)
+
+	parseInt := func(name string, dst *int) {
+		value, err := strconv.Atoi(strings.TrimSpace(r.PostForm.Get(name)))
+		if err != nil {
+			errs[name] = "must be a whole number"
+			return
+		}
+		*dst = value
+	}
+	parseInt("window_check_interval", &s.WindowCheckInterval)
+	parseInt("save_interval", &s.SaveInterval)
+	parseInt("min_title_duration", &s.MinTitleDuration)
+	parseInt("day_start_hour", &s.DayStartHour)
+
+	s.RecordWindowTitles = r.PostForm.Get("record_window_titles") == "true"
+
+	var excludedClasses conf.StringList
+	excludedClasses.Set(r.PostForm.Get("excluded_classes"))
+	s.ExcludedClasses = excludedClasses
+
+	categories, err := settings.ParseCategories(r.PostForm.Get("categories"))
+	if err != nil {
+		errs["categories"] = err.Error()
+	}
+	s.Categories = categories
+
+	return s, errs
+}
+
+func newSettingsForm(s settings.Settings) *templates.SettingsForm {
+	return &templates.SettingsForm{
+		WindowCheckInterval: strconv.Itoa(s.WindowCheckInterval),
+		SaveInterval:        strconv.Itoa(s.SaveInterval),
+		MinTitleDuration:    strconv.Itoa(s.MinTitleDuration),
+		RecordWindowTitles:  s.RecordWindowTitles,
+		ExcludedClasses:     strings.Join(s.ExcludedClasses, ", "),
+		DayStartHour:        strconv.Itoa(s.DayStartHour),
+		Categories:          settings.FormatCategories(s.Categories),
+	}
+}
+
+func newSettingsFormFromRequest(r *http.Request) *templates.SettingsForm {
+	return &templates.SettingsForm{
+		WindowCheckInterval: r.PostForm.Get("window_check_interval"),
+		SaveInterval:        r.PostForm.Get("save_interval"),
+		MinTitleDuration:    r.PostForm.Get("min_title_duration"),
+		RecordWindowTitles:  r.PostForm.Get("record_window_titles") == "true",
+		ExcludedClasses:     r.PostForm.Get("excluded_classes"),
+		DayStartHour:        r.PostForm.Get("day_start_hour"),
+		Categories:          r.PostForm.Get("categories"),
+	}
+}
@@ -0,0 +1,136 @@
+package httphandler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/services/devicesync"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+)
+
+// startedAt is used to report the uptime of the server.
+var startedAt = time.Now()
+
+// Status is what /status reports. The `telltime status` command decodes it
+// too, so the JSON field names are part of the API.
+type Status struct {
+	Status         string     `json:"status"`
+	Problems       []string   `json:"problems"`
+	Version        string     `json:"version"`
+	BuildTime      string     `json:"build_time"`
+	UptimeSecs     int64      `json:"uptime_secs"`
+	Backend        string     `json:"backend"`
+	Paused         bool       `json:"paused"`
+	OnDesktop      bool       `json:"on_desktop"`
+	MediaPlaying   bool       `json:"media_playing"`
+	LastWindowRead *time.Time `json:"last_window_read"`
+	LastSave       *time.Time `json:"last_save"`
+	PendingEvents  int        `json:"pending_events"`
+	TickErrors     int64      `json:"tick_errors"`
+	FailedSaves    int64      `json:"failed_saves"`
+	DBSizeBytes    int64      `json:"db_size_bytes"`
+	EventCount     int64      `json:"event_count"`
+	Sync           *SyncState `json:"sync,omitempty"`
+}
+
+// SyncState is reported if events are pushed to a sync server.
+type SyncState struct {
+	Server       string     `json:"server"`
+	LastSync     *time.Time `json:"last_sync"`
+	LastError    string     `json:"last_error,omitempty"`
+	PushedEvents int64      `json:"pushed_events"`
+}
+
+// HealthzGet responds with 200 if the tracker is recording activity and the
+// database is reachable and with 503 otherwise, which is what monitoring
+// tools expect.
+func (h *Handler) HealthzGet(w http.ResponseWriter, r *http.Request) {
+	status := h.status(r.Context())
+
+	httpStatus := http.StatusOK
+	if status.Status != StatusOK {
+		httpStatus = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, httpStatus, map[string]any{
+		"status":   status.Status,
+		"problems": status.Problems,
+	})
+}
+
+// StatusGet reports the state of the tracker and the database.
+func (h *Handler) StatusGet(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.status(r.Context()))
+}
+
+func (h *Handler) status(ctx context.Context) *Status {
+	now := time.Now()
+	buildTime, version := conf.VersionInfo()
+	trackerStats := activity.GetTrackerStats()
+
+	status := &Status{
+		Version:       version,
+		BuildTime:     buildTime,
+		UptimeSecs:    int64(now.Sub(startedAt).Seconds()),
+		Backend:       trackerStats.Backend,
+		Paused:        activity.Paused(),
+		OnDesktop:     activity.OnDesktop(),
+		MediaPlaying:  activity.MediaPlaying(),
+		PendingEvents: trackerStats.PendingEvents,
+		TickErrors:    trackerStats.TickErrors,
+		FailedSaves:   trackerStats.FailedSaves,
+		Problems: trackerStats.Problems(
+			now,
+			time.Duration(h.Config().WindowCheckInterval)*time.Second,
+			time.Duration(h.Config().SaveInterval)*time.Second,
+		),
+	}
+	if !trackerStats.LastWindowRead.IsZero() {
+		status.LastWindowRead = &trackerStats.LastWindowRead
+	}
+	if !trackerStats.LastSave.IsZero() {
+		status.LastSave = &trackerStats.LastSave
+	}
+
+	if h.Config().SyncServer != "" {
+		syncState := devicesync.GetState()
+		status.Sync = &SyncState{
+			Server:       syncState.Server,
+			LastError:    syncState.LastError,
+			PushedEvents: syncState.PushedEvents,
+		}
+		if !syncState.LastSuccess.IsZero() {
+			status.Sync.LastSync = &syncState.LastSuccess
+		}
+	}
+
+	var pageCount, pageSize int64
+	err := h.DB.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pageCount)
+	if err == nil {
+		err = h.DB.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize)
+	}
+	if err == nil {
+		status.DBSizeBytes = pageCount * pageSize
+		status.EventCount, err = h.Queries.CountEvents(ctx)
+	}
+	if err != nil {
+		status.Problems = append(status.Problems, fmt.Sprintf("the database can't be read: %v", err))
+	}
+
+	status.Status = StatusOK
+	if len(status.Problems) > 0 {
+		status.Status = StatusDegraded
+	} else {
+		status.Problems = []string{}
+	}
+
+	return status
+}
@@ -0,0 +1,80 @@
+package httphandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/services/devicesync"
+)
+
+type deviceResponse struct {
+	ID        string    `json:"id"`
+	Hostname  string    `json:"hostname"`
+	Local     bool      `json:"local"`
+	LastSeq   int64     `json:"last_seq"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// SyncEventsPost receives the events pushed by other devices if the server
+// accepts them. The response tells the device where its next batch starts.
+func (h *Handler) SyncEventsPost(w http.ResponseWriter, r *http.Request) {
+	if !h.Config().AcceptSync {
+		writeJSONError(w, http.StatusForbidden, "this server doesn't accept synced events (see -accept-sync)")
+		return
+	}
+
+	var batch devicesync.Batch
+	if err := decodeJSON(w, r, &batch); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	lastSeq, err := devicesync.Receive(r.Context(), h.DB, batch, time.Now())
+	if errors.Is(err, devicesync.ErrGap) {
+		writeJSON(w, http.StatusConflict, map[string]any{
+			"error":    err.Error(),
+			"last_seq": lastSeq,
+		})
+		return
+	} else if errors.Is(err, devicesync.ErrInvalidBatch) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		h.renderInternalServerError(w, r, fmt.Errorf("saving the synced events: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, devicesync.Ack{LastSeq: lastSeq})
+}
+
+// SyncDevicesGet lists this device and the devices that pushed events to it.
+func (h *Handler) SyncDevicesGet(w http.ResponseWriter, r *http.Request) {
+	if _, err := activity.LocalDevice(r.Context(), h.Queries, time.Now()); err != nil {
+		h.renderInternalServerError(w, r, fmt.Errorf("reading the device: %v", err))
+		return
+	}
+
+	devices, err := h.Queries.GetDevices(r.Context())
+	if err != nil {
+		h.renderInternalServerError(w, r, fmt.Errorf("reading the devices: %v", err))
+		return
+	}
+
+	resp := make([]deviceResponse, 0, len(devices))
+	for _, d := range devices {
+		resp = append(resp, deviceResponse{
+			ID:        d.ID,
+			Hostname:  d.Hostname,
+			Local:     d.IsLocal == 1,
+			LastSeq:   d.LastSeq,
+			FirstSeen: time.Unix(d.FirstSeenAt, 0),
+			LastSeen:  time.Unix(d.LastSeenAt, 0),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,99 @@
+package httphandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/services/team"
+	"github.com/bnuredini/telltime/internal/templates"
+)
+
+// teamPeriodsShown is how many weeks can be picked on the team page.
+const teamPeriodsShown = 52
+
+// TeamReportsPost receives the signed weekly reports of teammates if the
+// server accepts them.
+func (h *Handler) TeamReportsPost(w http.ResponseWriter, r *http.Request) {
+	if !h.Config().AcceptTeamReports {
+		writeJSONError(w, http.StatusForbidden, "this server doesn't accept team reports (see -accept-team-reports)")
+		return
+	}
+
+	var report team.Report
+	if err := decodeJSON(w, r, &report); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := team.Store(r.Context(), h.DB, &report, time.Now())
+	if errors.Is(err, team.ErrInvalidReport) || errors.Is(err, team.ErrBadSignature) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if errors.Is(err, team.ErrKeyMismatch) {
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		h.renderInternalServerError(w, r, fmt.Errorf("saving the team report: %v", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TeamReportsGet returns the combined reports for the week in the week query
+// parameter, which can be any day of it. The latest week is used without it.
+func (h *Handler) TeamReportsGet(w http.ResponseWriter, r *http.Request) {
+	summary, _, err := h.teamSummary(r)
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// TeamGet shows the combined reports of the team for a week.
+func (h *Handler) TeamGet(w http.ResponseWriter, r *http.Request) {
+	summary, periods, err := h.teamSummary(r)
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
+		return
+	}
+
+	tmplData := templates.NewData()
+	tmplData.TeamSummary = summary
+	tmplData.TeamPeriods = periods
+	tmplData.AcceptTeamReports = h.Config().AcceptTeamReports
+
+	err = templates.RenderPage(h.TemplateManager, w, templates.PageTeam, tmplData)
+	if err != nil {
+		h.renderInternalServerError(w, r, err)
+	}
+}
+
+func (h *Handler) teamSummary(r *http.Request) (*team.Summary, []string, error) {
+	periods, err := team.Periods(r.Context(), h.Queries, teamPeriodsShown)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading the team report periods: %v", err)
+	}
+
+	var periodStart string
+	if rawWeek := r.URL.Query().Get("week"); rawWeek != "" {
+		first, _ := team.WeekOf(parseISO8601Date(rawWeek, time.Now()))
+		periodStart = first.Format(team.DateLayout)
+	} else if len(periods) > 0 {
+		periodStart = periods[0]
+	} else {
+		first, _ := team.WeekOf(time.Now())
+		periodStart = first.Format(team.DateLayout)
+	}
+
+	summary, err := team.Combine(r.Context(), h.Queries, periodStart)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading the team reports: %v", err)
+	}
+
+	return summary, periods, nil
+}
@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// Up applies every up migration that hasn't been applied yet. The applied
+// version is recorded in schema_migrations using the same layout as
+// golang-migrate, so the CLI can still be used against the same database.
+func Up(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL, dirty INTEGER NOT NULL)`)
+	if err != nil {
+		return fmt.Errorf("creating schema_migrations: %v", err)
+	}
+
+	var currVersion int64
+	var dirty bool
+	err = db.QueryRow(`SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&currVersion, &dirty)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("reading the schema version: %v", err)
+	}
+	if dirty {
+		return fmt.Errorf("the database is marked dirty at version %d; fix it manually before starting", currVersion)
+	}
+
+	filePaths, err := fs.Glob(files, "sql/*.up.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(filePaths)
+
+	for _, filePath := range filePaths {
+		version, err := versionFromPath(filePath)
+		if err != nil {
+			return err
+		}
+		if version <= currVersion {
+			continue
+		}
+
+		stmts, err := files.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		slog.Info("applying migration", "file", path.Base(filePath))
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(string(stmts)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("applying %q: %v", filePath, err)
+		}
+		if _, err = tx.Exec(`DELETE FROM schema_migrations`); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err = tx.Exec(`INSERT INTO schema_migrations (version, dirty) VALUES (?, 0)`, version); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+
+		currVersion = version
+	}
+
+	return nil
+}
+
+func versionFromPath(filePath string) (int64, error) {
+	rawVersion, _, found := strings.Cut(path.Base(filePath), "_")
+	if !found {
+		return 0, fmt.Errorf("migration %q doesn't follow the <version>_<name>.up.sql format", filePath)
+	}
+
+	return strconv.ParseInt(rawVersion, 10, 64)
+}
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS event_edit (
+	id 			INTEGER PRIMARY KEY,
+	kind 		VARCHAR(16)  NOT NULL,
+	start_time 	INTEGER 	 NOT NULL,
+	duration 	INTEGER 	 NOT NULL,
+	label 		VARCHAR(255),
+	category 	VARCHAR(255),
+	note 		TEXT,
+	source 		VARCHAR(16)  NOT NULL,
+	created_at 	INTEGER 	 NOT NULL,
+	reverted_at INTEGER
+);
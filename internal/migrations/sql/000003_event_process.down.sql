@@ -0,0 +1,3 @@
+ALTER TABLE event DROP COLUMN cmdline;
+ALTER TABLE event DROP COLUMN exe_path;
+ALTER TABLE event DROP COLUMN pid;
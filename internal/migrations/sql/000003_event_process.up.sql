@@ -0,0 +1,3 @@
+ALTER TABLE event ADD COLUMN pid INTEGER;
+ALTER TABLE event ADD COLUMN exe_path VARCHAR(1024);
+ALTER TABLE event ADD COLUMN cmdline TEXT;
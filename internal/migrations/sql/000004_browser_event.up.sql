@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS browser_event (
+	id 			INTEGER PRIMARY KEY,
+	start_time 	INTEGER 	  NOT NULL,
+	browser 	VARCHAR(64),
+	url 		TEXT,
+	domain 		VARCHAR(255),
+	title 		VARCHAR(255),
+	audible 	INTEGER 	  NOT NULL DEFAULT 0,
+	incognito 	INTEGER 	  NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS browser_event_start_time_idx ON browser_event (start_time);
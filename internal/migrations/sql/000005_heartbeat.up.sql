@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS heartbeat (
+	id 			INTEGER PRIMARY KEY,
+	time 		INTEGER 	  NOT NULL,
+	entity 		TEXT 		  NOT NULL,
+	project 	VARCHAR(255),
+	language 	VARCHAR(64),
+	branch 		VARCHAR(255),
+	editor 		VARCHAR(64),
+	is_write 	INTEGER 	  NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS heartbeat_time_idx ON heartbeat (time);
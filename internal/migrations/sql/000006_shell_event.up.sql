@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS shell_event (
+	id 				INTEGER PRIMARY KEY,
+	start_time 		INTEGER 	  NOT NULL,
+	duration 		INTEGER 	  NOT NULL,
+	command 		TEXT 		  NOT NULL,
+	cwd 			TEXT,
+	repo 			TEXT,
+	exit_status 	INTEGER,
+	shell 			VARCHAR(16),
+	window_class 	VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS shell_event_start_time_idx ON shell_event (start_time);
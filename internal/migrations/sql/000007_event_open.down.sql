@@ -0,0 +1,2 @@
+DELETE FROM event WHERE is_open = 1;
+ALTER TABLE event DROP COLUMN is_open;
@@ -0,0 +1 @@
+ALTER TABLE event ADD COLUMN is_open INTEGER NOT NULL DEFAULT 0;
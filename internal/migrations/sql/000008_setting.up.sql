@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS setting (
+	name 			VARCHAR(64) PRIMARY KEY,
+	value 			TEXT 		  NOT NULL,
+	updated_at 		INTEGER 	  NOT NULL
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS password (
+	id 			INTEGER PRIMARY KEY CHECK (id = 1),
+	hash 		TEXT 		  NOT NULL,
+	updated_at 	INTEGER 	  NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS session (
+	token_hash 	TEXT PRIMARY KEY,
+	created_at 	INTEGER 	  NOT NULL,
+	expires_at 	INTEGER 	  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS session_expires_at_idx ON session (expires_at);
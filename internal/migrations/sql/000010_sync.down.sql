@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS sync_cursor;
+DROP INDEX IF EXISTS event_device_seq_idx;
+DELETE FROM event WHERE device_id IS NOT NULL;
+ALTER TABLE event DROP COLUMN seq;
+ALTER TABLE event DROP COLUMN device_id;
+DROP TABLE IF EXISTS device;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS device (
+	id 				VARCHAR(64)  PRIMARY KEY,
+	hostname 		VARCHAR(255) NOT NULL,
+	is_local 		INTEGER 	 NOT NULL DEFAULT 0,
+	last_seq 		INTEGER 	 NOT NULL DEFAULT 0,
+	first_seen_at 	INTEGER 	 NOT NULL,
+	last_seen_at 	INTEGER 	 NOT NULL
+);
+
+ALTER TABLE event ADD COLUMN device_id VARCHAR(64);
+ALTER TABLE event ADD COLUMN seq INTEGER;
+
+CREATE UNIQUE INDEX IF NOT EXISTS event_device_seq_idx ON event (device_id, seq);
+
+CREATE TABLE IF NOT EXISTS sync_cursor (
+	server_url 		TEXT PRIMARY KEY,
+	last_seq 		INTEGER 	 NOT NULL,
+	updated_at 		INTEGER 	 NOT NULL
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS event_device_start_time_idx;
+
+UPDATE event
+SET device_id = NULL
+WHERE device_id = (SELECT id FROM device WHERE is_local = 1);
+
+DROP INDEX IF EXISTS device_local_idx;
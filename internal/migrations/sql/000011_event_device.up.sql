@@ -0,0 +1,11 @@
+INSERT INTO device (id, hostname, is_local, first_seen_at, last_seen_at)
+SELECT lower(hex(randomblob(16))), '', 1, unixepoch(), unixepoch()
+WHERE NOT EXISTS (SELECT 1 FROM device WHERE is_local = 1);
+
+CREATE UNIQUE INDEX IF NOT EXISTS device_local_idx ON device (is_local) WHERE is_local = 1;
+
+UPDATE event
+SET device_id = (SELECT id FROM device WHERE is_local = 1)
+WHERE device_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS event_device_start_time_idx ON event (device_id, start_time);
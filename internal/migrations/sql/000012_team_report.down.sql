@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS team_report;
+DROP TABLE IF EXISTS team_member;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS team_member (
+	public_key 		TEXT 		 PRIMARY KEY,
+	person 			VARCHAR(255) NOT NULL UNIQUE,
+	first_seen_at 	INTEGER 	 NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS team_report (
+	public_key 		TEXT 		 NOT NULL REFERENCES team_member (public_key),
+	period_start 	VARCHAR(10)  NOT NULL,
+	period_end 		VARCHAR(10)  NOT NULL,
+	generated_at 	INTEGER 	 NOT NULL,
+	received_at 	INTEGER 	 NOT NULL,
+	document 		TEXT 		 NOT NULL,
+	PRIMARY KEY (public_key, period_start)
+);
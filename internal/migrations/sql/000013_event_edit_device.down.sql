@@ -0,0 +1 @@
+ALTER TABLE event_edit DROP COLUMN device_id;
@@ -0,0 +1,5 @@
+ALTER TABLE event_edit ADD COLUMN device_id VARCHAR(64);
+
+UPDATE event_edit
+SET device_id = (SELECT id FROM device WHERE is_local = 1)
+WHERE device_id IS NULL;
@@ -14,6 +14,229 @@ FROM event
 WHERE start_time BETWEEN sqlc.arg(start_time) AND sqlc.arg(end_time)
 ORDER BY start_time DESC;
 
+-- name: CountEvents :one
+SELECT COUNT(*)
+FROM event;
+
+-- name: CountEventsByStart :one
+SELECT COUNT(*)
+FROM event
+WHERE start_time = ? AND window_class = ? AND is_open = 0;
+
 -- name: InsertEvents :exec
-INSERT INTO event (start_time, window_class, window_title, duration)
-VALUES (?, ?, ?, ?);
+INSERT INTO event (start_time, window_class, window_title, duration, pid, exe_path, cmdline, device_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, (SELECT id FROM device WHERE is_local = 1));
+
+-- name: GetEventsInRange :many
+SELECT *
+FROM event
+WHERE start_time < sqlc.arg(end_time) AND start_time + duration > sqlc.arg(start_time) AND is_open = 0
+	AND (sqlc.narg(device_id) IS NULL OR device_id = sqlc.narg(device_id))
+ORDER BY start_time;
+
+-- name: GetOpenEvents :many
+SELECT *
+FROM event
+WHERE is_open = 1
+ORDER BY start_time;
+
+-- name: InsertOpenEvent :one
+INSERT INTO event (start_time, window_class, window_title, duration, pid, exe_path, cmdline, is_open, device_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, 1, (SELECT id FROM device WHERE is_local = 1))
+RETURNING id;
+
+-- name: CloseEvent :exec
+UPDATE event
+SET is_open = 0
+WHERE id = ?;
+
+-- name: UpdateEvent :exec
+UPDATE event
+SET window_title = ?, duration = ?, is_open = ?
+WHERE id = ?;
+
+-- name: GetEventEdit :one
+SELECT *
+FROM event_edit
+WHERE id = ?;
+
+-- name: GetEventEdits :many
+SELECT *
+FROM event_edit
+ORDER BY id DESC
+LIMIT ?;
+
+-- name: GetActiveEventEditsInRange :many
+SELECT *
+FROM event_edit
+WHERE reverted_at IS NULL
+	AND start_time < sqlc.arg(end_time)
+	AND start_time + duration > sqlc.arg(start_time)
+	AND (sqlc.narg(device_id) IS NULL OR device_id = sqlc.narg(device_id))
+ORDER BY id;
+
+-- name: InsertEventEdit :one
+INSERT INTO event_edit (kind, start_time, duration, label, category, note, source, created_at, device_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, COALESCE(sqlc.narg(device_id), (SELECT id FROM device WHERE is_local = 1)))
+RETURNING *;
+
+-- name: RevertEventEdit :execrows
+UPDATE event_edit
+SET reverted_at = ?
+WHERE id = ? AND reverted_at IS NULL;
+
+-- name: InsertBrowserEvent :exec
+INSERT INTO browser_event (start_time, browser, url, domain, title, audible, incognito)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetBrowserEventsInRange :many
+SELECT *
+FROM browser_event
+WHERE start_time < sqlc.arg(end_time)
+	AND start_time >= COALESCE(
+		(SELECT MAX(b.start_time) FROM browser_event b WHERE b.start_time <= sqlc.arg(start_time)),
+		0
+	)
+ORDER BY start_time;
+
+-- name: InsertHeartbeat :exec
+INSERT INTO heartbeat (time, entity, project, language, branch, editor, is_write)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetHeartbeatsInRange :many
+SELECT *
+FROM heartbeat
+WHERE time BETWEEN sqlc.arg(start_time) AND sqlc.arg(end_time)
+ORDER BY time;
+
+-- name: InsertShellEvent :exec
+INSERT INTO shell_event (start_time, duration, command, cwd, repo, exit_status, shell, window_class)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetShellEventsInRange :many
+SELECT *
+FROM shell_event
+WHERE start_time < sqlc.arg(end_time) AND start_time + duration >= sqlc.arg(start_time)
+ORDER BY start_time;
+
+-- name: GetSettings :many
+SELECT *
+FROM setting
+ORDER BY name;
+
+-- name: UpsertSetting :exec
+INSERT INTO setting (name, value, updated_at)
+VALUES (?, ?, ?)
+ON CONFLICT (name) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at;
+
+-- name: GetPasswordHash :one
+SELECT hash
+FROM password
+WHERE id = 1;
+
+-- name: UpsertPassword :exec
+INSERT INTO password (id, hash, updated_at)
+VALUES (1, ?, ?)
+ON CONFLICT (id) DO UPDATE SET hash = excluded.hash, updated_at = excluded.updated_at;
+
+-- name: DeletePassword :exec
+DELETE FROM password;
+
+-- name: InsertSession :exec
+INSERT INTO session (token_hash, created_at, expires_at)
+VALUES (?, ?, ?);
+
+-- name: GetSession :one
+SELECT *
+FROM session
+WHERE token_hash = ? AND expires_at > sqlc.arg(now);
+
+-- name: DeleteSession :exec
+DELETE FROM session
+WHERE token_hash = ?;
+
+-- name: DeleteSessions :exec
+DELETE FROM session;
+
+-- name: DeleteExpiredSessions :exec
+DELETE FROM session
+WHERE expires_at <= ?;
+
+-- name: GetLocalDevice :one
+SELECT *
+FROM device
+WHERE is_local = 1;
+
+-- name: GetDevice :one
+SELECT *
+FROM device
+WHERE id = ?;
+
+-- name: GetDevices :many
+SELECT *
+FROM device
+ORDER BY is_local DESC, hostname;
+
+-- name: InsertDevice :exec
+INSERT INTO device (id, hostname, is_local, first_seen_at, last_seen_at)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: UpdateDevice :exec
+UPDATE device
+SET hostname = ?, last_seq = ?, last_seen_at = ?
+WHERE id = ?;
+
+-- name: GetUnsyncedEvents :many
+SELECT *
+FROM event
+WHERE id > sqlc.arg(after_id) AND device_id = (SELECT id FROM device WHERE is_local = 1)
+ORDER BY id
+LIMIT ?;
+
+-- name: InsertSyncedEvent :execrows
+INSERT INTO event (start_time, window_class, window_title, duration, exe_path, cmdline, device_id, seq)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (device_id, seq) DO NOTHING;
+
+-- name: GetSyncCursor :one
+SELECT last_seq
+FROM sync_cursor
+WHERE server_url = ?;
+
+-- name: UpsertSyncCursor :exec
+INSERT INTO sync_cursor (server_url, last_seq, updated_at)
+VALUES (?, ?, ?)
+ON CONFLICT (server_url) DO UPDATE SET last_seq = excluded.last_seq, updated_at = excluded.updated_at;
+
+-- name: GetTeamMember :one
+SELECT *
+FROM team_member
+WHERE public_key = ?;
+
+-- name: GetTeamMemberByPerson :one
+SELECT *
+FROM team_member
+WHERE person = ?;
+
+-- name: InsertTeamMember :exec
+INSERT INTO team_member (public_key, person, first_seen_at)
+VALUES (?, ?, ?);
+
+-- name: UpsertTeamReport :exec
+INSERT INTO team_report (public_key, period_start, period_end, generated_at, received_at, document)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (public_key, period_start) DO UPDATE
+SET period_end = excluded.period_end, generated_at = excluded.generated_at, received_at = excluded.received_at, document = excluded.document
+WHERE excluded.generated_at >= team_report.generated_at;
+
+-- name: GetTeamReports :many
+SELECT *
+FROM team_report
+WHERE period_start = ?
+ORDER BY public_key;
+
+-- name: GetTeamReportPeriods :many
+SELECT DISTINCT period_start
+FROM team_report
+ORDER BY period_start DESC
+LIMIT ?;
@@ -5,23 +5,31 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"path/filepath"
 	"runtime"
-	"strings"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/bnuredini/telltime/internal/conf"
 	"github.com/bnuredini/telltime/internal/dbgen"
 )
 
-const (
-	StartOfDayHour = 4
-)
+// UncategorizedName is the category reported for time that wasn't assigned
+// to a category.
+const UncategorizedName = "Uncategorized"
+
+// DesktopName is the program that time spent on the desktop is recorded as
+// if RecordDesktop is set.
+const DesktopName = "Desktop"
 
 type WindowChangeEvent struct {
 	StartTimestamp time.Time
 	WindowID       string
 	WindowClass    string
 	WindowName     string
+	Process        Process
 	DurationSecs   uint32
 }
 
@@ -30,6 +38,25 @@ type WindowInfo struct {
 	WindowID       string
 	WindowClass    string
 	WindowName     string
+	Process        Process
+
+	// Desktop is set if no window is focused or the focused window is the
+	// desktop itself.
+	Desktop bool
+
+	// pendingName is a new title that hasn't lasted long enough yet to be
+	// recorded as a separate event.
+	pendingName  string
+	pendingSince time.Time
+}
+
+// Process describes the program that owns a window. Window classes vary
+// across distros and Electron apps often share generic ones, so the
+// executable is a more reliable way to tell programs apart.
+type Process struct {
+	PID     int
+	ExePath string
+	Cmdline string
 }
 
 type Stat struct {
@@ -46,6 +73,63 @@ type CategoryStat struct {
 type ProgramStat struct {
 	Stat
 	ProgramName string
+	ExePath     string
+	Domains     []*DomainStat
+}
+
+type DomainStat struct {
+	Domain       string
+	DurationSecs int64
+}
+
+// ProgramGrouping determines what counts as the same program in the stats.
+type ProgramGrouping string
+
+const (
+	GroupByClass      ProgramGrouping = "class"
+	GroupByExecutable ProgramGrouping = "executable"
+)
+
+// Options control how the recorded data is turned into a timeline and stats.
+type Options struct {
+	GroupBy ProgramGrouping
+
+	// BrowserClasses are the window classes whose time is split up by the
+	// tabs reported by the browser extension.
+	BrowserClasses []string
+
+	// EditorClasses are the window classes during which heartbeats from
+	// editor plugins count as coding time.
+	EditorClasses    []string
+	HeartbeatTimeout time.Duration
+
+	// Categories maps window classes to the category their time is
+	// assigned to unless an edit says otherwise.
+	Categories map[string]string
+
+	DayStartHour int
+
+	// DeviceID limits the stats to the events of one device. Events of every
+	// device are included if it's empty.
+	DeviceID string
+}
+
+func OptionsFromConfig(config *conf.Config) Options {
+	categories := make(map[string]string)
+	for _, rule := range config.Categories {
+		for _, class := range rule.Classes {
+			categories[class] = rule.Category
+		}
+	}
+
+	return Options{
+		GroupBy:          GroupByClass,
+		BrowserClasses:   config.BrowserClasses,
+		EditorClasses:    config.EditorClasses,
+		HeartbeatTimeout: time.Duration(config.HeartbeatTimeout) * time.Second,
+		Categories:       categories,
+		DayStartHour:     config.DayStartHour,
+	}
 }
 
 const (
@@ -55,103 +139,285 @@ const (
 	OS_WINDOWS = "windows"
 )
 
+// saveChunkSize is how many events are written per transaction so that
+// saving a large backlog, e.g. after replaying the journal, doesn't keep the
+// database locked for long.
+const saveChunkSize = 500
+
+// mu guards windowChanges, lastWindow, openEvent, onDesktop, pausedFor and
+// mediaPlaying. windowChanges and lastWindow are written by the tracker and
+// read by the HTTP handlers.
+var mu sync.Mutex
 var windowChanges []*WindowChangeEvent
 var lastWindow *WindowInfo
+var openEvent *savedOpenEvent
+
+// onDesktop is set while the desktop is focused and isn't recorded. There's
+// no current window then.
+var onDesktop bool
+
+// savedOpenEvent identifies the row that holds the current window.
+type savedOpenEvent struct {
+	ID          int64
+	Start       time.Time
+	WindowClass string
+}
+
+func (o *savedOpenEvent) matches(start time.Time, windowClass string) bool {
+	return o != nil && o.Start.Unix() == start.Unix() && o.WindowClass == windowClass
+}
+
+// Run tracks the focused window until ctx is canceled and saves what was
+// recorded before returning. It returns early with an error if the window
+// can't be tracked at all.
+func Run(ctx context.Context, db *sql.DB, config *conf.Config) error {
+	// The tracker works on a copy that only it modifies, see UpdateConfig.
+	trackerConfig := *config
+	config = &trackerConfig
+
+	if err := restoreOpenEvent(db); err != nil {
+		return fmt.Errorf("restoring the open event: %v", err)
+	}
+
+	if config.JournalPath != "" {
+		if err := openJournal(db, config.JournalPath); err != nil {
+			return err
+		}
+		defer closeJournal()
+	}
 
-func Init(db *sql.DB, config *conf.Config) {
 	switch runtime.GOOS {
 	case OS_LINUX:
-		initLinux(db, config)
+		return runLinux(ctx, db, config)
 	case OS_WINDOWS:
-		initWindows(db, config)
+		return runWindows(ctx, db, config)
 	case OS_DARWIN:
-		initMacOS(db, config)
+		return runMacOS(ctx, db, config)
 	}
+
+	return fmt.Errorf("tracking windows on %s isn't supported", runtime.GOOS)
 }
 
 func Save(db *sql.DB) error {
-	if len(windowChanges) == 0 {
-		return nil
+	mu.Lock()
+	defer mu.Unlock()
+
+	return save(db, time.Now())
+}
+
+// save writes the closed events to the database and keeps the open row of the
+// current window up to date. The caller must hold mu.
+func save(db *sql.DB, now time.Time) error {
+	saveStart := time.Now()
+	err := saveEvents(db, now)
+	recordSave(time.Since(saveStart), err)
+	if err != nil {
+		slog.Error("failed to save data", "err", err)
+		return err
 	}
 
-	// TODO: Refreshing the /activity page will lead many rows even if there are no window changes.
-	if lastWindow != nil {
-		eventForLastWindow := &WindowChangeEvent{
-			StartTimestamp: lastWindow.StartTimestamp,
-			WindowID:       lastWindow.WindowID,
-			WindowClass:    lastWindow.WindowClass,
-			WindowName:     lastWindow.WindowName,
-			DurationSecs:   uint32(time.Since(lastWindow.StartTimestamp).Seconds()),
+	if journal != nil {
+		if err := journal.truncate(); err != nil {
+			slog.Error("failed to empty the journal", "err", err)
 		}
-		windowChanges = append(windowChanges, eventForLastWindow)
 	}
 
-	values := make([]string, 0, len(windowChanges))
-	args := make([]any, 0, len(windowChanges))
+	return nil
+}
+
+func saveEvents(db *sql.DB, now time.Time) error {
+	ctx := context.Background()
 
-	for i, event := range windowChanges {
-		values = append(
-			values,
-			fmt.Sprintf("($%d, $%d, $%d, $%d)", i*4+1, i*4+2, i*4+3, i*4+4),
-		)
+	// Events are removed from windowChanges as soon as their chunk is
+	// committed so that retrying after an error doesn't save them twice.
+	for len(windowChanges) > 0 {
+		chunk := windowChanges[:min(saveChunkSize, len(windowChanges))]
+		closedOpenEvent := false
 
-		args = append(
-			args,
-			event.StartTimestamp.Unix(),
-			event.WindowClass,
-			event.WindowName,
-			event.DurationSecs,
-		)
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		q := dbgen.New(tx)
+
+		for _, e := range chunk {
+			if openEvent.matches(e.StartTimestamp, e.WindowClass) {
+				err = q.UpdateEvent(ctx, dbgen.UpdateEventParams{
+					WindowTitle: toNullString(e.WindowName),
+					Duration:    int64(e.DurationSecs),
+					IsOpen:      0,
+					ID:          openEvent.ID,
+				})
+				closedOpenEvent = true
+			} else {
+				err = q.InsertEvents(ctx, dbgen.InsertEventsParams{
+					StartTime:   e.StartTimestamp.Unix(),
+					WindowClass: e.WindowClass,
+					WindowTitle: toNullString(e.WindowName),
+					Duration:    int64(e.DurationSecs),
+					Pid:         sql.NullInt64{Int64: int64(e.Process.PID), Valid: e.Process.PID != 0},
+					ExePath:     toNullString(e.Process.ExePath),
+					Cmdline:     toNullString(e.Process.Cmdline),
+				})
+			}
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+
+		if closedOpenEvent {
+			openEvent = nil
+		}
+		windowChanges = windowChanges[len(chunk):]
 	}
 
-	stmt := fmt.Sprintf(
-		"INSERT INTO event (start_time, window_class, window_title, duration) VALUES %v",
-		strings.Join(values, ","),
-	)
+	return saveOpenEvent(ctx, dbgen.New(db), now)
+}
+
+// saveOpenEvent stores the time spent in the current window so far in a row
+// that's marked as open. The row is updated on every save and closed when
+// the window is, so the current window is never saved twice. Open rows are
+// left out of the timeline since the current window is read from memory.
+func saveOpenEvent(ctx context.Context, q *dbgen.Queries, now time.Time) error {
+	if lastWindow != nil && openEvent.matches(lastWindow.StartTimestamp, lastWindow.WindowClass) {
+		return q.UpdateEvent(ctx, dbgen.UpdateEventParams{
+			WindowTitle: toNullString(lastWindow.WindowName),
+			Duration:    int64(now.Sub(lastWindow.StartTimestamp).Seconds()),
+			IsOpen:      1,
+			ID:          openEvent.ID,
+		})
+	}
+
+	// The open row belongs to a window that isn't current anymore and whose
+	// event didn't replace it, e.g. because it was left behind by a crash.
+	// It's closed with the duration that was saved last.
+	if openEvent != nil {
+		if err := q.CloseEvent(ctx, openEvent.ID); err != nil {
+			return err
+		}
+		openEvent = nil
+	}
+
+	if lastWindow == nil {
+		return nil
+	}
 
-	_, err := db.Exec(stmt, args...)
+	id, err := q.InsertOpenEvent(ctx, dbgen.InsertOpenEventParams{
+		StartTime:   lastWindow.StartTimestamp.Unix(),
+		WindowClass: lastWindow.WindowClass,
+		WindowTitle: toNullString(lastWindow.WindowName),
+		Duration:    int64(now.Sub(lastWindow.StartTimestamp).Seconds()),
+		Pid:         sql.NullInt64{Int64: int64(lastWindow.Process.PID), Valid: lastWindow.Process.PID != 0},
+		ExePath:     toNullString(lastWindow.Process.ExePath),
+		Cmdline:     toNullString(lastWindow.Process.Cmdline),
+	})
+	if err != nil {
+		return err
+	}
+
+	openEvent = &savedOpenEvent{
+		ID:          id,
+		Start:       lastWindow.StartTimestamp,
+		WindowClass: lastWindow.WindowClass,
+	}
+
+	return nil
+}
+
+// restoreOpenEvent picks up the open rows left behind by a run that didn't
+// shut down cleanly. The last one is closed by the next save, or replaced by
+// the complete event if the journal has it.
+func restoreOpenEvent(db *sql.DB) error {
+	ctx := context.Background()
+	q := dbgen.New(db)
+
+	events, err := q.GetOpenEvents(ctx)
 	if err != nil {
-		slog.Error("failed to save data", "err", err)
 		return err
 	}
 
-	windowChanges = []*WindowChangeEvent{}
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i, e := range events {
+		if i < len(events)-1 {
+			if err := q.CloseEvent(ctx, e.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		openEvent = &savedOpenEvent{
+			ID:          e.ID,
+			Start:       time.Unix(e.StartTime, 0),
+			WindowClass: e.WindowClass,
+		}
+	}
 
 	return nil
 }
 
+// GetProgramStats sums up the time spent in each program. When grouping by
+// executable, segments without a known executable (e.g. manual entries) fall
+// back to their label. Browser time is further broken down by domain.
 func GetProgramStats(
 	ctx context.Context,
 	q *dbgen.Queries,
 	start time.Time,
 	end time.Time,
+	opts Options,
 ) ([]*ProgramStat, error) {
 
 	result := []*ProgramStat{}
-	events, err := q.GetEventsByTime(
-		ctx,
-		dbgen.GetEventsByTimeParams{
-			StartTime: start.Unix(),
-			EndTime:   end.Unix(),
-		},
-	)
+	segments, err := GetTimeline(ctx, q, start, end, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	stats := make(map[string]*ProgramStat)
-	for _, e := range events {
-		stat, ok := stats[e.WindowClass]
+	domainStats := make(map[string]map[string]*DomainStat)
+	for _, s := range segments {
+		key, name := s.Label, s.Label
+		if opts.GroupBy == GroupByExecutable && s.ExePath != "" {
+			key, name = s.ExePath, filepath.Base(s.ExePath)
+		}
+
+		stat, ok := stats[key]
 		if !ok {
-			stats[e.WindowClass] = &ProgramStat{ProgramName: e.WindowClass}
-		} else {
-			stat.DurationSecs += e.Duration
+			stat = &ProgramStat{ProgramName: name}
+			if key == s.ExePath {
+				stat.ExePath = s.ExePath
+			}
+			stats[key] = stat
+		}
+
+		stat.DurationSecs += s.DurationSecs()
+
+		if s.Domain != "" {
+			if domainStats[key] == nil {
+				domainStats[key] = make(map[string]*DomainStat)
+			}
+			domainStat, ok := domainStats[key][s.Domain]
+			if !ok {
+				domainStat = &DomainStat{Domain: s.Domain}
+				domainStats[key][s.Domain] = domainStat
+				stat.Domains = append(stat.Domains, domainStat)
+			}
+			domainStat.DurationSecs += s.DurationSecs()
 		}
 	}
 
 	for _, s := range stats {
 		s.StartTimestamp = start
 		s.EndTimestamp = end
+		sort.Slice(s.Domains, func(i, j int) bool {
+			return s.Domains[i].DurationSecs > s.Domains[j].DurationSecs
+		})
 
 		result = append(result, s)
 	}
@@ -159,81 +425,268 @@ func GetProgramStats(
 	return result, nil
 }
 
+// GetCategoryStats sums up the time spent in each category. Categories are
+// assigned through edits, so segments without one are grouped together.
+func GetCategoryStats(
+	ctx context.Context,
+	q *dbgen.Queries,
+	start time.Time,
+	end time.Time,
+	opts Options,
+) ([]*CategoryStat, error) {
+	segments, err := GetTimeline(ctx, q, start, end, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]*CategoryStat)
+	result := []*CategoryStat{}
+	for _, s := range segments {
+		name := s.Category
+		if name == "" {
+			name = UncategorizedName
+		}
+
+		stat, ok := stats[name]
+		if !ok {
+			stat = &CategoryStat{
+				Stat:         Stat{StartTimestamp: start, EndTimestamp: end},
+				CategoryName: name,
+			}
+			stats[name] = stat
+			result = append(result, stat)
+		}
+
+		stat.DurationSecs += s.DurationSecs()
+	}
+
+	return result, nil
+}
+
 func GetProgramStatsForDate(
 	ctx context.Context,
 	q *dbgen.Queries,
 	date time.Time,
+	opts Options,
 ) ([]*ProgramStat, error) {
-	start, end := GetDayIntervalForDate(date)
-	return GetProgramStats(ctx, q, start, end)
+	start, end := GetDayIntervalForDate(date, opts.DayStartHour)
+	return GetProgramStats(ctx, q, start, end, opts)
 }
 
-func GetDayInterval() (start time.Time, end time.Time) {
+// GetDayInterval returns the interval from the start of the current day up to
+// now. Days start at dayStartHour, so right after midnight it's still the
+// previous day.
+func GetDayInterval(dayStartHour int) (start time.Time, end time.Time) {
 	now := time.Now()
-	if now.Hour() >= StartOfDayHour {
-		start = time.Date(now.Year(), now.Month(), now.Day(), 4, 0, 0, 0, now.Location())
+	if now.Hour() >= dayStartHour {
+		start = time.Date(now.Year(), now.Month(), now.Day(), dayStartHour, 0, 0, 0, now.Location())
 	} else {
 		yesterday := now.AddDate(0, 0, -1)
-		start = time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 4, 0, 0, 0, now.Location())
+		start = time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), dayStartHour, 0, 0, 0, now.Location())
 	}
 
 	return start, now
 }
 
-func GetDayIntervalForDate(date time.Time) (start time.Time, end time.Time) {
-	start = time.Date(date.Year(), date.Month(), date.Day(), 4, 0, 0, 0, date.Location())
-	end = time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 59, date.Location())
+// GetDayIntervalForDate returns the interval of the day that starts at
+// dayStartHour on date and ends when the next one starts.
+func GetDayIntervalForDate(date time.Time, dayStartHour int) (start time.Time, end time.Time) {
+	start = time.Date(date.Year(), date.Month(), date.Day(), dayStartHour, 0, 0, 0, date.Location())
+	end = start.AddDate(0, 0, 1)
 
 	return
 }
 
-func updateCurrentActivity(windowID, windowClass, windowName string) {
+// CurrentWindow returns the window that's currently focused. The second value
+// is false if the tracker hasn't observed any window yet or if no window is
+// being recorded, e.g. because the desktop is focused.
+func CurrentWindow() (WindowInfo, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if lastWindow == nil {
+		return WindowInfo{}, false
+	}
+
+	return *lastWindow, true
+}
+
+// OnDesktop reports whether the desktop is focused and not being recorded.
+func OnDesktop() bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return onDesktop
+}
+
+// pendingSegments returns the events that haven't been saved yet, including
+// the one for the current window, which ends at now.
+func pendingSegments(now time.Time) []*Segment {
+	mu.Lock()
+	defer mu.Unlock()
+
+	segments := make([]*Segment, 0, len(windowChanges)+1)
+	for _, e := range windowChanges {
+		segments = append(segments, &Segment{
+			Start:   e.StartTimestamp,
+			End:     e.StartTimestamp.Add(time.Duration(e.DurationSecs) * time.Second),
+			Label:   e.WindowClass,
+			Title:   e.WindowName,
+			ExePath: e.Process.ExePath,
+		})
+	}
+
+	if lastWindow != nil {
+		segments = append(segments, &Segment{
+			Start:   lastWindow.StartTimestamp,
+			End:     now,
+			Label:   lastWindow.WindowClass,
+			Title:   lastWindow.WindowName,
+			ExePath: lastWindow.Process.ExePath,
+		})
+	}
+
+	return segments
+}
+
+func updateCurrentActivity(config *conf.Config, window WindowInfo) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(pausedFor) > 0 {
+		return
+	}
+
+	recordWindow(time.Now(), config, window)
+}
+
+// recordWindow updates the current window and closes the previous event when
+// the window changes. If titles are recorded, a title change within the same
+// window starts a new event too, but only once the new title has lasted for
+// MinTitleDuration. Until then it's kept as pending so that titles that
+// change every second (e.g. progress counters) don't produce a flood of tiny
+// events. The caller must hold mu.
+//
+// Time spent on the desktop isn't screen time, so the current event is closed
+// when the desktop is focused, unless RecordDesktop is set. In that case the
+// desktop is recorded like any other window. Windows of excluded classes
+// aren't recorded either.
+func recordWindow(now time.Time, config *conf.Config, window WindowInfo) {
+	settleTitle(now, config)
+
+	excluded := slices.Contains(config.ExcludedClasses, window.WindowClass)
+	if excluded || (window.Desktop && !config.RecordDesktop) {
+		if lastWindow != nil || onDesktop != window.Desktop {
+			slog.Debug("stopped recording", "desktop", window.Desktop, "windowClass", window.WindowClass)
+			breakCurrentActivity(now, config)
+			onDesktop = window.Desktop
+			notifySubscribers()
+		}
+
+		return
+	}
+	onDesktop = false
+
+	if window.Desktop {
+		window = WindowInfo{
+			WindowID:    DesktopName,
+			WindowClass: DesktopName,
+			Desktop:     true,
+		}
+	}
+
 	firstEvent := lastWindow == nil
-	windowChanged := lastWindow != nil && lastWindow.WindowID != windowID
+	windowChanged := lastWindow != nil && lastWindow.WindowID != window.WindowID
 
-	var newWindow *WindowInfo
 	if firstEvent || windowChanged {
-		newWindow = &WindowInfo{
-			StartTimestamp: time.Now(),
-			WindowID:       windowID,
-			WindowClass:    windowClass,
-			WindowName:     windowName,
+		if windowChanged {
+			slog.Debug(
+				"window changed",
+				"windowID", window.WindowID,
+				"windowClass", window.WindowClass,
+				"windowName", window.WindowName,
+				"exePath", window.Process.ExePath,
+			)
+			addWindowChange(closeWindow(lastWindow, now))
 		}
+
+		window.StartTimestamp = now
+		window.pendingName = ""
+		window.pendingSince = time.Time{}
+		lastWindow = &window
+		notifySubscribers()
+
+		return
 	}
 
-	if windowChanged {
-		slog.Debug("window changed", "windowID", windowID, "windowClass", windowClass, "windowName", windowName)
+	if !config.RecordWindowTitles {
+		return
+	}
 
-		event := &WindowChangeEvent{
-			StartTimestamp: lastWindow.StartTimestamp,
-			WindowID:       lastWindow.WindowID,
-			WindowClass:    lastWindow.WindowClass,
-			WindowName:     lastWindow.WindowName,
-			DurationSecs:   uint32(time.Since(lastWindow.StartTimestamp).Seconds()),
-		}
-		windowChanges = append(windowChanges, event)
+	switch window.WindowName {
+	case lastWindow.WindowName:
+		lastWindow.pendingName = ""
+		lastWindow.pendingSince = time.Time{}
+	case lastWindow.pendingName:
+	default:
+		slog.Debug("window title changed", "windowID", window.WindowID, "windowName", window.WindowName)
+		lastWindow.pendingName = window.WindowName
+		lastWindow.pendingSince = now
+	}
+}
+
+// settleTitle closes the current event where the pending title appeared if
+// that title has lasted long enough by now. The caller must hold mu.
+func settleTitle(now time.Time, config *conf.Config) {
+	if lastWindow == nil || lastWindow.pendingSince.IsZero() {
+		return
+	}
+
+	minTitleDuration := time.Duration(config.MinTitleDuration) * time.Second
+	if now.Sub(lastWindow.pendingSince) < minTitleDuration {
+		return
 	}
 
-	if newWindow != nil {
-		lastWindow = newWindow
+	addWindowChange(closeWindow(lastWindow, lastWindow.pendingSince))
+	lastWindow = &WindowInfo{
+		StartTimestamp: lastWindow.pendingSince,
+		WindowID:       lastWindow.WindowID,
+		WindowClass:    lastWindow.WindowClass,
+		WindowName:     lastWindow.pendingName,
+		Process:        lastWindow.Process,
 	}
+	notifySubscribers()
 }
 
-func handleGracefulShutdown(db *sql.DB) {
+func closeWindow(w *WindowInfo, end time.Time) *WindowChangeEvent {
+	return &WindowChangeEvent{
+		StartTimestamp: w.StartTimestamp,
+		WindowID:       w.WindowID,
+		WindowClass:    w.WindowClass,
+		WindowName:     w.WindowName,
+		Process:        w.Process,
+		DurationSecs:   uint32(end.Sub(w.StartTimestamp).Seconds()),
+	}
+}
+
+// handleGracefulShutdown closes the event for the current window and saves
+// everything that hasn't been saved yet.
+func handleGracefulShutdown(db *sql.DB, config *conf.Config) error {
 	slog.Info("graceful shutdown: cleaning up...")
 
+	mu.Lock()
+	defer mu.Unlock()
+
 	if lastWindow != nil {
-		event := &WindowChangeEvent{
-			StartTimestamp: lastWindow.StartTimestamp,
-			WindowID:       lastWindow.WindowID,
-			WindowClass:    lastWindow.WindowClass,
-			WindowName:     lastWindow.WindowName,
-			DurationSecs:   uint32(time.Since(lastWindow.StartTimestamp).Seconds()),
-		}
-		windowChanges = append(windowChanges, event)
+		now := time.Now()
+		settleTitle(now, config)
+		addWindowChange(closeWindow(lastWindow, now))
+		lastWindow = nil
+	}
 
-		if err := Save(db); err != nil {
-			slog.Error("shutting down: failed to save activity data", "err", err)
-		}
+	if err := save(db, time.Now()); err != nil {
+		return fmt.Errorf("shutting down: failed to save activity data: %v", err)
 	}
+
+	return nil
 }
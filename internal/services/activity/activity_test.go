@@ -0,0 +1,373 @@
+package activity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+func TestRecordWindowTitleChanges(t *testing.T) {
+	base := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	at := func(secs int) time.Time {
+		return base.Add(time.Duration(secs) * time.Second)
+	}
+
+	type observation struct {
+		secs     int
+		windowID string
+		title    string
+	}
+	type want struct {
+		startSecs int
+		duration  uint32
+		title     string
+	}
+
+	tests := []struct {
+		name         string
+		recordTitles bool
+		observations []observation
+		want         []want
+	}{
+		{
+			name:         "titles are ignored unless recorded",
+			recordTitles: false,
+			observations: []observation{{0, "1", "a"}, {30, "1", "b"}, {60, "2", "c"}},
+			want:         []want{{0, 60, "a"}},
+		},
+		{
+			name:         "a lasting title change starts a new event",
+			recordTitles: true,
+			observations: []observation{{0, "1", "a"}, {30, "1", "b"}, {60, "2", "c"}},
+			want:         []want{{0, 30, "a"}, {30, 30, "b"}},
+		},
+		{
+			name:         "short-lived titles are folded into the current event",
+			recordTitles: true,
+			observations: []observation{{0, "1", "a"}, {30, "1", "1%"}, {31, "1", "2%"}, {32, "1", "3%"}, {33, "2", "c"}},
+			want:         []want{{0, 33, "a"}},
+		},
+		{
+			name:         "going back to the previous title cancels the pending one",
+			recordTitles: true,
+			observations: []observation{{0, "1", "a"}, {30, "1", "b"}, {35, "1", "a"}, {60, "2", "c"}},
+			want:         []want{{0, 60, "a"}},
+		},
+		{
+			name:         "a title that settles after some noise starts where it first appeared",
+			recordTitles: true,
+			observations: []observation{{0, "1", "a"}, {30, "1", "1%"}, {31, "1", "done"}, {50, "1", "done"}, {60, "2", "c"}},
+			want:         []want{{0, 31, "a"}, {31, 29, "done"}},
+		},
+	}
+
+	for _, tt := range tests {
+		windowChanges = nil
+		lastWindow = nil
+
+		config := &conf.Config{RecordWindowTitles: tt.recordTitles, MinTitleDuration: 10}
+		for _, o := range tt.observations {
+			recordWindow(at(o.secs), config, WindowInfo{WindowID: o.windowID, WindowClass: "class", WindowName: o.title})
+		}
+
+		if len(windowChanges) != len(tt.want) {
+			t.Errorf("%s: got %d events, want %d", tt.name, len(windowChanges), len(tt.want))
+			continue
+		}
+
+		for i, w := range tt.want {
+			got := windowChanges[i]
+			if !got.StartTimestamp.Equal(at(w.startSecs)) || got.DurationSecs != w.duration || got.WindowName != w.title {
+				t.Errorf(
+					"%s: event %d: got=%v+%ds %q, want=%v+%ds %q",
+					tt.name, i,
+					got.StartTimestamp.Sub(base), got.DurationSecs, got.WindowName,
+					at(w.startSecs).Sub(base), w.duration, w.title,
+				)
+			}
+		}
+	}
+
+	windowChanges = nil
+	lastWindow = nil
+}
+
+func TestSaveDoesNotDoubleCount(t *testing.T) {
+	windowChanges = nil
+	lastWindow = nil
+	openEvent = nil
+	t.Cleanup(func() {
+		windowChanges = nil
+		lastWindow = nil
+		openEvent = nil
+	})
+
+	ctx := context.Background()
+	db := testutil.OpenDB(t)
+	q := dbgen.New(db)
+	config := &conf.Config{}
+
+	// The timeline includes the current window up to time.Now(), so the
+	// events have to be recent.
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	at := func(mins int) time.Time {
+		return base.Add(time.Duration(mins) * time.Minute)
+	}
+
+	openEvents := func() []dbgen.Event {
+		t.Helper()
+
+		events, err := q.GetOpenEvents(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return events
+	}
+	eventCount := func() int64 {
+		t.Helper()
+
+		count, err := q.CountEvents(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return count
+	}
+
+	recordWindow(at(0), config, WindowInfo{WindowID: "1", WindowClass: "firefox"})
+	for _, mins := range []int{10, 10, 20} {
+		if err := save(db, at(mins)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := openEvents(); len(got) != 1 || got[0].Duration != 20*60 {
+		t.Fatalf("got open events=%+v, want one lasting 20m", got)
+	}
+	if got := eventCount(); got != 1 {
+		t.Fatalf("got %d events after saving the same window repeatedly, want 1", got)
+	}
+
+	// Closing the window turns the open row into a regular one.
+	recordWindow(at(30), config, WindowInfo{WindowID: "2", WindowClass: "Alacritty"})
+	if err := save(db, at(40)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := eventCount(); got != 2 {
+		t.Fatalf("got %d events, want 2", got)
+	}
+	if got := openEvents(); len(got) != 1 || got[0].WindowClass != "Alacritty" {
+		t.Fatalf("got open events=%+v, want one for Alacritty", got)
+	}
+
+	segments, err := GetTimeline(ctx, q, base, time.Now().Add(time.Hour), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total time.Duration
+	for _, s := range segments {
+		total += s.End.Sub(s.Start)
+	}
+	if elapsed := time.Since(base); total < elapsed-2*time.Second || total > elapsed+2*time.Second {
+		t.Errorf("got %v on the timeline, want %v", total, elapsed)
+	}
+
+	if err := handleGracefulShutdown(db, config); err != nil {
+		t.Fatal(err)
+	}
+	if got := openEvents(); len(got) != 0 {
+		t.Errorf("got open events=%+v after shutting down, want none", got)
+	}
+	if got := eventCount(); got != 2 {
+		t.Errorf("got %d events after shutting down, want 2", got)
+	}
+}
+
+func TestSaveLargeBatch(t *testing.T) {
+	windowChanges = nil
+	lastWindow = nil
+	openEvent = nil
+	t.Cleanup(func() {
+		windowChanges = nil
+		lastWindow = nil
+		openEvent = nil
+	})
+
+	db := testutil.OpenDB(t)
+	base := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+
+	// Enough events for several chunks and a partial one.
+	const n = 2*saveChunkSize + 10
+	for i := range n {
+		windowChanges = append(windowChanges, &WindowChangeEvent{
+			StartTimestamp: base.Add(time.Duration(i) * time.Second),
+			WindowClass:    "class",
+			DurationSecs:   1,
+		})
+	}
+
+	if err := save(db, base.Add(n*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := dbgen.New(db).CountEvents(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != n {
+		t.Errorf("got %d events, want %d", count, n)
+	}
+	if len(windowChanges) != 0 {
+		t.Errorf("got %d unsaved events, want 0", len(windowChanges))
+	}
+}
+
+func TestRestoreOpenEvent(t *testing.T) {
+	windowChanges = nil
+	lastWindow = nil
+	openEvent = nil
+	t.Cleanup(func() {
+		windowChanges = nil
+		lastWindow = nil
+		openEvent = nil
+	})
+
+	ctx := context.Background()
+	db := testutil.OpenDB(t)
+	q := dbgen.New(db)
+	base := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+
+	// Rows that were left open by a run that crashed.
+	for i, class := range []string{"firefox", "Emacs"} {
+		_, err := q.InsertOpenEvent(ctx, dbgen.InsertOpenEventParams{
+			StartTime:   base.Add(time.Duration(i) * time.Minute).Unix(),
+			WindowClass: class,
+			Duration:    60,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := restoreOpenEvent(db); err != nil {
+		t.Fatal(err)
+	}
+
+	recordWindow(base.Add(time.Hour), &conf.Config{}, WindowInfo{WindowID: "1", WindowClass: "Code"})
+	if err := save(db, base.Add(time.Hour+time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := q.GetOpenEvents(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].WindowClass != "Code" {
+		t.Errorf("got open events=%+v, want one for Code", events)
+	}
+
+	closed, err := q.GetEventsInRange(ctx, dbgen.GetEventsInRangeParams{
+		StartTime: base.Unix(),
+		EndTime:   base.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(closed) != 2 {
+		t.Errorf("got %d closed events, want the 2 that were left open", len(closed))
+	}
+}
+
+func TestRecordWindowDesktop(t *testing.T) {
+	windowChanges = nil
+	lastWindow = nil
+	onDesktop = false
+	t.Cleanup(func() {
+		windowChanges = nil
+		lastWindow = nil
+		onDesktop = false
+	})
+
+	start := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	at := func(mins int) time.Time {
+		return start.Add(time.Duration(mins) * time.Minute)
+	}
+
+	config := &conf.Config{}
+	recordWindow(at(0), config, WindowInfo{WindowID: "1", WindowClass: "firefox"})
+	recordWindow(at(10), config, WindowInfo{Desktop: true})
+	recordWindow(at(15), config, WindowInfo{Desktop: true})
+
+	if lastWindow != nil || !onDesktop {
+		t.Fatalf("got lastWindow=%+v, onDesktop=%v, want no window on the desktop", lastWindow, onDesktop)
+	}
+	if len(windowChanges) != 1 || windowChanges[0].DurationSecs != 600 {
+		t.Fatalf("got windowChanges=%+v, want one event lasting 10m", windowChanges)
+	}
+
+	recordWindow(at(20), config, WindowInfo{WindowID: "1", WindowClass: "firefox"})
+	if onDesktop || lastWindow == nil || !lastWindow.StartTimestamp.Equal(at(20)) {
+		t.Fatalf("got lastWindow=%+v, onDesktop=%v, want firefox from 09:20", lastWindow, onDesktop)
+	}
+
+	// The desktop counts as a program of its own if it's recorded.
+	config.RecordDesktop = true
+	recordWindow(at(30), config, WindowInfo{WindowID: "42", WindowClass: "Xfdesktop", Desktop: true})
+	recordWindow(at(40), config, WindowInfo{Desktop: true})
+	recordWindow(at(50), config, WindowInfo{WindowID: "1", WindowClass: "firefox"})
+
+	if len(windowChanges) != 3 {
+		t.Fatalf("got %d events, want 3", len(windowChanges))
+	}
+	if e := windowChanges[2]; e.WindowClass != DesktopName || e.DurationSecs != 20*60 {
+		t.Errorf("got %+v, want the desktop for 20m", e)
+	}
+}
+
+func TestRecordWindowExcludedClasses(t *testing.T) {
+	windowChanges = nil
+	lastWindow = nil
+	t.Cleanup(func() {
+		windowChanges = nil
+		lastWindow = nil
+	})
+
+	start := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	config := &conf.Config{ExcludedClasses: conf.StringList{"KeePassXC"}}
+
+	recordWindow(start, config, WindowInfo{WindowID: "1", WindowClass: "firefox"})
+	recordWindow(start.Add(5*time.Minute), config, WindowInfo{WindowID: "2", WindowClass: "KeePassXC"})
+
+	if lastWindow != nil {
+		t.Fatalf("got lastWindow=%+v, want none while an excluded window is focused", lastWindow)
+	}
+
+	recordWindow(start.Add(6*time.Minute), config, WindowInfo{WindowID: "1", WindowClass: "firefox"})
+
+	if len(windowChanges) != 1 || windowChanges[0].DurationSecs != 5*60 {
+		t.Errorf("got windowChanges=%+v, want one event lasting 5m", windowChanges)
+	}
+	if lastWindow == nil || !lastWindow.StartTimestamp.Equal(start.Add(6*time.Minute)) {
+		t.Errorf("got lastWindow=%+v, want firefox from 09:06", lastWindow)
+	}
+}
+
+func TestGetDayIntervalForDate(t *testing.T) {
+	date := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+
+	start, end := GetDayIntervalForDate(date, 6)
+
+	if want := time.Date(2025, 6, 2, 6, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("got start=%v, want %v", start, want)
+	}
+	if want := time.Date(2025, 6, 3, 6, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Errorf("got end=%v, want %v", end, want)
+	}
+}
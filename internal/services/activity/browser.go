@@ -0,0 +1,147 @@
+package activity
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+)
+
+// PrivateDomain is what incognito tabs are reported as when their details
+// aren't recorded.
+const PrivateDomain = "(private)"
+
+// TabEvent is what the browser extension sends when the active tab changes.
+type TabEvent struct {
+	Timestamp time.Time
+	Browser   string
+	URL       string
+	Domain    string
+	Title     string
+	Audible   bool
+	Incognito bool
+}
+
+// SaveTabEvent stores a tab change. Unless the user opted in, incognito tabs
+// are stored without their URL, domain and title. The change itself is still
+// kept so that the time spent in a private tab isn't attributed to the tab
+// that was active before it. When window titles aren't recorded, only the
+// domain of a tab is stored since its URL tells as much as its title.
+func SaveTabEvent(ctx context.Context, q *dbgen.Queries, config *conf.Config, e TabEvent) error {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+
+	if e.Domain == "" {
+		e.Domain = DomainFromURL(e.URL)
+	}
+
+	if e.Incognito && !config.RecordIncognitoTabs {
+		e.URL = ""
+		e.Title = ""
+		e.Domain = PrivateDomain
+	}
+
+	if !config.RecordWindowTitles {
+		e.URL = ""
+		e.Title = ""
+	}
+
+	err := q.InsertBrowserEvent(ctx, dbgen.InsertBrowserEventParams{
+		StartTime: e.Timestamp.Unix(),
+		Browser:   toNullString(e.Browser),
+		Url:       toNullString(e.URL),
+		Domain:    toNullString(e.Domain),
+		Title:     toNullString(e.Title),
+		Audible:   boolToInt(e.Audible),
+		Incognito: boolToInt(e.Incognito),
+	})
+	if err != nil {
+		return fmt.Errorf("saving the tab event: %v", err)
+	}
+
+	return nil
+}
+
+// DomainFromURL returns the host of rawURL without the "www." prefix. URLs
+// without a host (e.g. "about:config") are reported by their scheme.
+func DomainFromURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	if host := u.Hostname(); host != "" {
+		return strings.TrimPrefix(host, "www.")
+	}
+
+	return u.Scheme
+}
+
+// SplitByTabs splits the segments of browser windows wherever the active tab
+// changed and sets their domain. Every tab event marks the start of a tab that
+// stays active until the next one. Tab changes that happen while the browser
+// isn't focused don't count towards anything. The segments of other devices
+// are left as they are.
+func SplitByTabs(segments []*Segment, tabEvents []dbgen.BrowserEvent, browserClasses []string) []*Segment {
+	if len(tabEvents) == 0 {
+		return segments
+	}
+
+	result := make([]*Segment, 0, len(segments))
+	for _, s := range segments {
+		if s.Remote || !slices.Contains(browserClasses, s.Label) {
+			result = append(result, s)
+			continue
+		}
+
+		cursor := s.Start
+		for i, tab := range tabEvents {
+			tabStart := time.Unix(tab.StartTime, 0)
+			tabEnd := s.End
+			if i+1 < len(tabEvents) {
+				tabEnd = time.Unix(tabEvents[i+1].StartTime, 0)
+			}
+
+			partStart := maxTime(cursor, tabStart)
+			partEnd := minTime(s.End, tabEnd)
+			if !partEnd.After(partStart) {
+				continue
+			}
+
+			if partStart.After(cursor) {
+				before := *s
+				before.Start, before.End = cursor, partStart
+				result = append(result, &before)
+			}
+
+			part := *s
+			part.Start, part.End = partStart, partEnd
+			part.Domain = tab.Domain.String
+			result = append(result, &part)
+
+			cursor = partEnd
+		}
+
+		if s.End.After(cursor) {
+			after := *s
+			after.Start = cursor
+			result = append(result, &after)
+		}
+	}
+
+	return result
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
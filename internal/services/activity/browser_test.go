@@ -0,0 +1,120 @@
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+func TestSaveTabEventWithoutTitles(t *testing.T) {
+	ctx := context.Background()
+	q := dbgen.New(testutil.OpenDB(t))
+	start := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+
+	err := SaveTabEvent(ctx, q, &conf.Config{}, TabEvent{
+		Timestamp: start,
+		URL:       "https://github.com/bnuredini/telltime/issues/1",
+		Title:     "Some issue",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := q.GetBrowserEventsInRange(ctx, dbgen.GetBrowserEventsInRangeParams{
+		StartTime: start.Unix(),
+		EndTime:   start.Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+
+	e := events[0]
+	if e.Domain.String != "github.com" || e.Url.Valid || e.Title.Valid {
+		t.Errorf("got domain=%q url=%q title=%q, want only the domain", e.Domain.String, e.Url.String, e.Title.String)
+	}
+}
+
+func TestSplitByTabs(t *testing.T) {
+	base := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	at := func(mins int) time.Time {
+		return base.Add(time.Duration(mins) * time.Minute)
+	}
+	tab := func(mins int, domain string) dbgen.BrowserEvent {
+		return dbgen.BrowserEvent{
+			StartTime: at(mins).Unix(),
+			Domain:    sql.NullString{String: domain, Valid: true},
+		}
+	}
+
+	segments := []*Segment{
+		{Start: at(0), End: at(20), Label: "firefox"},
+		{Start: at(20), End: at(40), Label: "Alacritty"},
+		{Start: at(40), End: at(60), Label: "firefox"},
+	}
+	tabs := []dbgen.BrowserEvent{
+		tab(5, "github.com"),
+		tab(10, "go.dev"),
+		// Switching tabs while the terminal is focused doesn't count.
+		tab(30, "news.ycombinator.com"),
+		tab(50, PrivateDomain),
+	}
+
+	got := SplitByTabs(segments, tabs, []string{"firefox"})
+
+	want := []struct {
+		startMins int
+		endMins   int
+		label     string
+		domain    string
+	}{
+		{0, 5, "firefox", ""},
+		{5, 10, "firefox", "github.com"},
+		{10, 20, "firefox", "go.dev"},
+		{20, 40, "Alacritty", ""},
+		{40, 50, "firefox", "news.ycombinator.com"},
+		{50, 60, "firefox", PrivateDomain},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d segments, want %d", len(got), len(want))
+	}
+
+	for i, w := range want {
+		s := got[i]
+		if !s.Start.Equal(at(w.startMins)) || !s.End.Equal(at(w.endMins)) || s.Label != w.label || s.Domain != w.domain {
+			t.Errorf(
+				"segment %d: got=%v-%v %q %q, want=%v-%v %q %q",
+				i,
+				s.Start.Format("15:04"), s.End.Format("15:04"), s.Label, s.Domain,
+				at(w.startMins).Format("15:04"), at(w.endMins).Format("15:04"), w.label, w.domain,
+			)
+		}
+	}
+}
+
+func TestDomainFromURL(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{"https://www.github.com/bnuredini/telltime", "github.com"},
+		{"https://go.dev/doc/", "go.dev"},
+		{"http://localhost:8000/activity", "localhost"},
+		{"about:config", "about"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got, want := DomainFromURL(tt.input), tt.expectedOutput; got != want {
+			t.Errorf("input=%v, got=%v, want=%v", tt.input, got, want)
+		}
+	}
+}
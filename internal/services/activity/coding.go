@@ -0,0 +1,153 @@
+package activity
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+)
+
+// Heartbeat is what editor plugins send while a file is being worked on.
+type Heartbeat struct {
+	Time     time.Time
+	Entity   string
+	Project  string
+	Language string
+	Branch   string
+	Editor   string
+	IsWrite  bool
+}
+
+type CodingStat struct {
+	Name         string
+	DurationSecs int64
+}
+
+type CodingStats struct {
+	TotalSecs  int64
+	ByLanguage []*CodingStat
+	ByProject  []*CodingStat
+}
+
+func SaveHeartbeat(ctx context.Context, q *dbgen.Queries, h Heartbeat) error {
+	if strings.TrimSpace(h.Entity) == "" {
+		return fmt.Errorf("a heartbeat needs an entity")
+	}
+	if h.Time.IsZero() {
+		h.Time = time.Now()
+	}
+
+	return q.InsertHeartbeat(ctx, dbgen.InsertHeartbeatParams{
+		Time:     h.Time.Unix(),
+		Entity:   h.Entity,
+		Project:  toNullString(h.Project),
+		Language: toNullString(h.Language),
+		Branch:   toNullString(h.Branch),
+		Editor:   toNullString(h.Editor),
+		IsWrite:  boolToInt(h.IsWrite),
+	})
+}
+
+// GetCodingStats returns the time spent coding between start and end, broken
+// down by language and project.
+func GetCodingStats(
+	ctx context.Context,
+	q *dbgen.Queries,
+	start time.Time,
+	end time.Time,
+	opts Options,
+) (*CodingStats, error) {
+	segments, err := GetTimeline(ctx, q, start, end, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Heartbeats sent shortly before start still cover some time after it.
+	heartbeats, err := q.GetHeartbeatsInRange(ctx, dbgen.GetHeartbeatsInRangeParams{
+		StartTime: start.Add(-opts.HeartbeatTimeout).Unix(),
+		EndTime:   end.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return CodingStatsFromHeartbeats(segments, heartbeats, opts), nil
+}
+
+// CodingStatsFromHeartbeats attributes the time after every heartbeat to its
+// language and project until the next heartbeat arrives or the timeout
+// passes, whichever comes first. Only the parts of that time during which an
+// editor was focused count, so leaving the editor open in the background
+// doesn't inflate the numbers. Heartbeats are only recorded by this device,
+// so the editors focused on other devices don't count.
+func CodingStatsFromHeartbeats(segments []*Segment, heartbeats []dbgen.Heartbeat, opts Options) *CodingStats {
+	byLanguage := make(map[string]*CodingStat)
+	byProject := make(map[string]*CodingStat)
+	stats := &CodingStats{}
+
+	add := func(m map[string]*CodingStat, name string, secs int64) {
+		if name == "" {
+			name = "Unknown"
+		}
+		stat, ok := m[name]
+		if !ok {
+			stat = &CodingStat{Name: name}
+			m[name] = stat
+		}
+		stat.DurationSecs += secs
+	}
+
+	for i, h := range heartbeats {
+		hStart := time.Unix(h.Time, 0)
+		hEnd := hStart.Add(opts.HeartbeatTimeout)
+		if i+1 < len(heartbeats) {
+			hEnd = minTime(hEnd, time.Unix(heartbeats[i+1].Time, 0))
+		}
+
+		var secs int64
+		for _, s := range segments {
+			if s.Remote || !slices.Contains(opts.EditorClasses, s.Label) {
+				continue
+			}
+
+			overlapStart := maxTime(s.Start, hStart)
+			overlapEnd := minTime(s.End, hEnd)
+			if overlapEnd.After(overlapStart) {
+				secs += int64(overlapEnd.Sub(overlapStart).Seconds())
+			}
+		}
+
+		if secs == 0 {
+			continue
+		}
+
+		stats.TotalSecs += secs
+		add(byLanguage, h.Language.String, secs)
+		add(byProject, h.Project.String, secs)
+	}
+
+	stats.ByLanguage = sortedCodingStats(byLanguage)
+	stats.ByProject = sortedCodingStats(byProject)
+
+	return stats
+}
+
+func sortedCodingStats(m map[string]*CodingStat) []*CodingStat {
+	result := make([]*CodingStat, 0, len(m))
+	for _, stat := range m {
+		result = append(result, stat)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].DurationSecs == result[j].DurationSecs {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].DurationSecs > result[j].DurationSecs
+	})
+
+	return result
+}
@@ -0,0 +1,66 @@
+package activity
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+)
+
+func TestCodingStatsFromHeartbeats(t *testing.T) {
+	base := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	at := func(mins int) time.Time {
+		return base.Add(time.Duration(mins) * time.Minute)
+	}
+	heartbeat := func(mins int, language string, project string) dbgen.Heartbeat {
+		return dbgen.Heartbeat{
+			Time:     at(mins).Unix(),
+			Language: sql.NullString{String: language, Valid: true},
+			Project:  sql.NullString{String: project, Valid: true},
+		}
+	}
+
+	segments := []*Segment{
+		{Start: at(0), End: at(10), Label: "Code"},
+		{Start: at(10), End: at(20), Label: "firefox"},
+		{Start: at(20), End: at(30), Label: "Code"},
+	}
+	heartbeats := []dbgen.Heartbeat{
+		heartbeat(0, "Go", "telltime"),
+		heartbeat(1, "Go", "telltime"),
+		// Covers 8-10 only: the browser is focused afterwards.
+		heartbeat(8, "SQL", "telltime"),
+		heartbeat(22, "Go", "dotfiles"),
+	}
+	opts := Options{EditorClasses: []string{"Code"}, HeartbeatTimeout: 2 * time.Minute}
+
+	got := CodingStatsFromHeartbeats(segments, heartbeats, opts)
+
+	// 0-1 and 1-3 for Go, 8-10 for SQL and 22-24 for Go.
+	if want := int64(7 * 60); got.TotalSecs != want {
+		t.Errorf("TotalSecs: got=%v, want=%v", got.TotalSecs, want)
+	}
+
+	wantLanguages := []CodingStat{{"Go", 5 * 60}, {"SQL", 2 * 60}}
+	wantProjects := []CodingStat{{"telltime", 5 * 60}, {"dotfiles", 2 * 60}}
+
+	for _, tt := range []struct {
+		name string
+		got  []*CodingStat
+		want []CodingStat
+	}{
+		{"ByLanguage", got.ByLanguage, wantLanguages},
+		{"ByProject", got.ByProject, wantProjects},
+	} {
+		if len(tt.got) != len(tt.want) {
+			t.Errorf("%s: got %d stats, want %d", tt.name, len(tt.got), len(tt.want))
+			continue
+		}
+		for i, w := range tt.want {
+			if *tt.got[i] != w {
+				t.Errorf("%s[%d]: got=%+v, want=%+v", tt.name, i, *tt.got[i], w)
+			}
+		}
+	}
+}
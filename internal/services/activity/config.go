@@ -0,0 +1,67 @@
+package activity
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+)
+
+// configUpdates holds the latest configuration that the tracker hasn't picked
+// up yet. configUpdatesMu makes replacing it atomic.
+var configUpdates = make(chan conf.Config, 1)
+var configUpdatesMu sync.Mutex
+
+// latestConfig is the configuration last handed to UpdateConfig. It's nil
+// until the settings change.
+var latestConfig atomic.Pointer[conf.Config]
+
+// UpdateConfig hands a new configuration to the running tracker, which
+// applies it without restarting. If the tracker hasn't picked up the previous
+// one yet, it's replaced.
+func UpdateConfig(config conf.Config) {
+	configUpdatesMu.Lock()
+	defer configUpdatesMu.Unlock()
+
+	// It's stored before the tracker can receive it so that readers of
+	// CurrentConfig stop reading the configuration that the tracker modifies.
+	latestConfig.Store(&config)
+
+	select {
+	case <-configUpdates:
+	default:
+	}
+	configUpdates <- config
+}
+
+// CurrentConfig returns the configuration last handed to UpdateConfig, or
+// startup if the settings haven't changed since the start. Background jobs
+// other than the tracker use it to pick up the changes. It must not be
+// modified.
+func CurrentConfig(startup *conf.Config) *conf.Config {
+	if config := latestConfig.Load(); config != nil {
+		return config
+	}
+
+	return startup
+}
+
+// applyConfig replaces the configuration of the tracker and resets the tickers
+// in case the intervals changed. It must be called from the tracker loop. The
+// Windows tracker has no tickers and passes nil.
+func applyConfig(config *conf.Config, newConfig conf.Config, windowCheckTicker *time.Ticker, saveTicker *time.Ticker) {
+	slog.Info("applying the new configuration")
+
+	mu.Lock()
+	*config = newConfig
+	mu.Unlock()
+
+	if windowCheckTicker != nil {
+		windowCheckTicker.Reset(time.Duration(config.WindowCheckInterval) * time.Second)
+	}
+	if saveTicker != nil {
+		saveTicker.Reset(time.Duration(config.SaveInterval) * time.Second)
+	}
+}
@@ -0,0 +1,67 @@
+package activity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+)
+
+func TestUpdateConfig(t *testing.T) {
+	t.Cleanup(func() {
+		select {
+		case <-configUpdates:
+		default:
+		}
+		latestConfig.Store(nil)
+	})
+
+	startup := &conf.Config{WindowCheckInterval: 5, SaveInterval: 300}
+	if CurrentConfig(startup) != startup {
+		t.Error("got a different configuration before any update, want the startup one")
+	}
+
+	// Only the latest configuration is applied if the tracker is busy.
+	UpdateConfig(conf.Config{WindowCheckInterval: 1, SaveInterval: 10})
+	UpdateConfig(conf.Config{WindowCheckInterval: 2, SaveInterval: 20})
+
+	windowCheckTicker := time.NewTicker(time.Hour)
+	defer windowCheckTicker.Stop()
+	saveTicker := time.NewTicker(time.Hour)
+	defer saveTicker.Stop()
+
+	if got := CurrentConfig(startup); got.WindowCheckInterval != 2 || got.SaveInterval != 20 {
+		t.Errorf("got current configuration %+v, want the latest one", got)
+	}
+
+	config := startup
+	select {
+	case newConfig := <-configUpdates:
+		applyConfig(config, newConfig, windowCheckTicker, saveTicker)
+	default:
+		t.Fatal("no configuration was handed to the tracker")
+	}
+
+	if config.WindowCheckInterval != 2 || config.SaveInterval != 20 {
+		t.Errorf("got %+v, want the latest configuration", config)
+	}
+
+	select {
+	case <-configUpdates:
+		t.Error("got another configuration, want only the latest one")
+	default:
+	}
+
+	// The tickers run with the new intervals.
+	select {
+	case <-windowCheckTicker.C:
+	case <-time.After(5 * time.Second):
+		t.Error("the window check ticker wasn't reset")
+	}
+
+	// The Windows tracker has no tickers.
+	applyConfig(config, conf.Config{WindowCheckInterval: 3, SaveInterval: 30}, nil, nil)
+	if config.WindowCheckInterval != 3 || config.SaveInterval != 30 {
+		t.Errorf("got %+v, want the configuration applied without tickers", config)
+	}
+}
@@ -0,0 +1,45 @@
+package activity
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+)
+
+// LocalDevice returns the device this database belongs to. Its ID is generated
+// when the database is created, so it stays the same even if the hostname
+// changes. The hostname is updated whenever it did.
+func LocalDevice(ctx context.Context, q *dbgen.Queries, now time.Time) (dbgen.Device, error) {
+	device, err := q.GetLocalDevice(ctx)
+	if err != nil {
+		return device, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == device.Hostname {
+		return device, nil
+	}
+
+	device.Hostname = hostname
+	device.LastSeenAt = now.Unix()
+	err = q.UpdateDevice(ctx, dbgen.UpdateDeviceParams{
+		Hostname:   device.Hostname,
+		LastSeq:    device.LastSeq,
+		LastSeenAt: device.LastSeenAt,
+		ID:         device.ID,
+	})
+
+	return device, err
+}
+
+// DeviceNames maps the IDs of the devices to their hostnames.
+func DeviceNames(devices []dbgen.Device) map[string]string {
+	names := make(map[string]string, len(devices))
+	for _, d := range devices {
+		names[d.ID] = d.Hostname
+	}
+
+	return names
+}
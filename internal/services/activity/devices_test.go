@@ -0,0 +1,105 @@
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+func TestLocalDeviceUpdatesHostname(t *testing.T) {
+	ctx := context.Background()
+	q := dbgen.New(testutil.OpenDB(t))
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	device, err := LocalDevice(ctx, q, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.ID == "" || device.IsLocal != 1 {
+		t.Fatalf("got %+v, want the local device created by the migrations", device)
+	}
+	if device.Hostname != hostname {
+		t.Errorf("got hostname %q, want %q", device.Hostname, hostname)
+	}
+
+	stored, err := q.GetLocalDevice(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ID != device.ID || stored.Hostname != hostname {
+		t.Errorf("stored %+v, want %+v", stored, device)
+	}
+}
+
+func TestGetTimelineFiltersByDevice(t *testing.T) {
+	windowChanges = nil
+	lastWindow = nil
+	t.Cleanup(func() {
+		windowChanges = nil
+		lastWindow = nil
+	})
+
+	ctx := context.Background()
+	q := dbgen.New(testutil.OpenDB(t))
+	base := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+
+	err := q.InsertEvents(ctx, dbgen.InsertEventsParams{
+		StartTime:   base.Unix(),
+		WindowClass: "firefox",
+		Duration:    60 * 60,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = q.InsertSyncedEvent(ctx, dbgen.InsertSyncedEventParams{
+		StartTime:   base.Unix(),
+		WindowClass: "firefox",
+		Duration:    30 * 60,
+		DeviceID:    sql.NullString{String: "laptop", Valid: true},
+		Seq:         sql.NullInt64{Int64: 1, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := q.GetLocalDevice(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		deviceID string
+		want     int64
+	}{
+		{"", 90 * 60},
+		{local.ID, 60 * 60},
+		{"laptop", 30 * 60},
+	}
+
+	for _, tt := range tests {
+		segments, err := GetTimeline(ctx, q, base, base.Add(time.Hour), Options{DeviceID: tt.deviceID})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var total int64
+		for _, s := range segments {
+			total += s.DurationSecs()
+			if s.Remote != (s.DeviceID != local.ID) {
+				t.Errorf("segment of %q has Remote %v", s.DeviceID, s.Remote)
+			}
+		}
+		if total != tt.want {
+			t.Errorf("device %q: got %ds, want %ds", tt.deviceID, total, tt.want)
+		}
+	}
+}
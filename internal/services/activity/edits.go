@@ -0,0 +1,349 @@
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+)
+
+// Edits are stored in the event_edit table as an overlay on top of the
+// recorded events. Recorded rows are never touched; reverting an edit only
+// marks it as reverted so that the full history stays auditable. Every edit
+// belongs to a device and only changes the timeline of that device.
+const (
+	EditKindAdd     = "add"
+	EditKindRelabel = "relabel"
+	EditKindSplit   = "split"
+	EditKindDelete  = "delete"
+)
+
+const (
+	EditSourceWeb = "web"
+	EditSourceCLI = "cli"
+)
+
+var ErrInvalidEdit = errors.New("invalid edit")
+
+// Segment is a stretch of time on the timeline after edits have been applied.
+// EventID is 0 for manual entries. Remote is set for the segments of other
+// devices, which can't be matched with the browser tabs and editor heartbeats
+// recorded here.
+type Segment struct {
+	EventID  int64
+	DeviceID string
+	Remote   bool
+	Start    time.Time
+	End      time.Time
+	Label    string
+	Title    string
+	ExePath  string
+	Domain   string
+	Category string
+	Manual   bool
+	Edited   bool
+}
+
+func (s *Segment) DurationSecs() int64 {
+	return int64(s.End.Sub(s.Start).Seconds())
+}
+
+// EditParams describes an edit. An empty DeviceID stands for the local
+// device.
+type EditParams struct {
+	DeviceID string
+	Start    time.Time
+	End      time.Time
+	Label    string
+	Category string
+	Note     string
+	Source   string
+}
+
+func AddManualEntry(ctx context.Context, q *dbgen.Queries, p EditParams) (dbgen.EventEdit, error) {
+	if strings.TrimSpace(p.Label) == "" {
+		return dbgen.EventEdit{}, fmt.Errorf("%w: a manual entry needs a label", ErrInvalidEdit)
+	}
+
+	return insertEdit(ctx, q, EditKindAdd, p)
+}
+
+func Relabel(ctx context.Context, q *dbgen.Queries, p EditParams) (dbgen.EventEdit, error) {
+	if strings.TrimSpace(p.Label) == "" && strings.TrimSpace(p.Category) == "" {
+		return dbgen.EventEdit{}, fmt.Errorf("%w: relabeling needs a label or a category", ErrInvalidEdit)
+	}
+
+	return insertEdit(ctx, q, EditKindRelabel, p)
+}
+
+// Split relabels everything in [at, end) so that the stretch of time that was
+// recorded as one event is reported as two.
+func Split(ctx context.Context, q *dbgen.Queries, at time.Time, p EditParams) (dbgen.EventEdit, error) {
+	if !at.After(p.Start) || !at.Before(p.End) {
+		return dbgen.EventEdit{}, fmt.Errorf(
+			"%w: the split point must be between %v and %v",
+			ErrInvalidEdit,
+			p.Start.Format(time.DateTime),
+			p.End.Format(time.DateTime),
+		)
+	}
+	if strings.TrimSpace(p.Label) == "" && strings.TrimSpace(p.Category) == "" {
+		return dbgen.EventEdit{}, fmt.Errorf("%w: splitting needs a label or a category for the second part", ErrInvalidEdit)
+	}
+
+	p.Start = at
+	return insertEdit(ctx, q, EditKindSplit, p)
+}
+
+func DeleteRange(ctx context.Context, q *dbgen.Queries, p EditParams) (dbgen.EventEdit, error) {
+	return insertEdit(ctx, q, EditKindDelete, p)
+}
+
+func RevertEdit(ctx context.Context, q *dbgen.Queries, id int64) error {
+	affected, err := q.RevertEventEdit(ctx, dbgen.RevertEventEditParams{
+		RevertedAt: sql.NullInt64{Int64: time.Now().Unix(), Valid: true},
+		ID:         id,
+	})
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: edit %d doesn't exist or was already reverted", ErrInvalidEdit, id)
+	}
+
+	return nil
+}
+
+// EventRange returns the time range covered by a recorded event and the
+// device that recorded it.
+func EventRange(
+	ctx context.Context,
+	q *dbgen.Queries,
+	eventID int64,
+) (start time.Time, end time.Time, deviceID string, err error) {
+	event, err := q.GetEvent(ctx, eventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return start, end, deviceID, fmt.Errorf("%w: event %d doesn't exist", ErrInvalidEdit, eventID)
+	} else if err != nil {
+		return start, end, deviceID, err
+	}
+
+	start = time.Unix(event.StartTime, 0)
+	end = start.Add(time.Duration(event.Duration) * time.Second)
+
+	return start, end, event.DeviceID.String, nil
+}
+
+func insertEdit(ctx context.Context, q *dbgen.Queries, kind string, p EditParams) (dbgen.EventEdit, error) {
+	if !p.End.After(p.Start) {
+		return dbgen.EventEdit{}, fmt.Errorf("%w: the end must be after the start", ErrInvalidEdit)
+	}
+	if p.Source == "" {
+		p.Source = EditSourceWeb
+	}
+	if p.DeviceID != "" {
+		_, err := q.GetDevice(ctx, p.DeviceID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return dbgen.EventEdit{}, fmt.Errorf("%w: device %q doesn't exist", ErrInvalidEdit, p.DeviceID)
+		} else if err != nil {
+			return dbgen.EventEdit{}, err
+		}
+	}
+
+	return q.InsertEventEdit(ctx, dbgen.InsertEventEditParams{
+		Kind:      kind,
+		StartTime: p.Start.Unix(),
+		Duration:  int64(p.End.Sub(p.Start).Seconds()),
+		Label:     toNullString(p.Label),
+		Category:  toNullString(p.Category),
+		Note:      toNullString(p.Note),
+		Source:    p.Source,
+		CreatedAt: time.Now().Unix(),
+		DeviceID:  sql.NullString{String: p.DeviceID, Valid: p.DeviceID != ""},
+	})
+}
+
+// GetTimeline returns the segments between start and end with every active
+// edit applied, clipped to the interval and ordered by start time. Events that
+// are still in memory are included, so callers don't need to save first.
+func GetTimeline(
+	ctx context.Context,
+	q *dbgen.Queries,
+	start time.Time,
+	end time.Time,
+	opts Options,
+) ([]*Segment, error) {
+	localDevice, err := q.GetLocalDevice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading the local device: %v", err)
+	}
+
+	events, err := q.GetEventsInRange(ctx, dbgen.GetEventsInRangeParams{
+		StartTime: start.Unix(),
+		EndTime:   end.Unix(),
+		DeviceID:  sql.NullString{String: opts.DeviceID, Valid: opts.DeviceID != ""},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	edits, err := q.GetActiveEventEditsInRange(ctx, dbgen.GetActiveEventEditsInRangeParams{
+		StartTime: start.Unix(),
+		EndTime:   end.Unix(),
+		DeviceID:  sql.NullString{String: opts.DeviceID, Valid: opts.DeviceID != ""},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]*Segment, 0, len(events))
+	for _, e := range events {
+		eventStart := time.Unix(e.StartTime, 0)
+		segments = append(segments, &Segment{
+			EventID:  e.ID,
+			DeviceID: e.DeviceID.String,
+			Remote:   e.DeviceID.String != localDevice.ID,
+			Start:    eventStart,
+			End:      eventStart.Add(time.Duration(e.Duration) * time.Second),
+			Label:    e.WindowClass,
+			Title:    e.WindowTitle.String,
+			ExePath:  e.ExePath.String,
+		})
+	}
+
+	if opts.DeviceID == "" || opts.DeviceID == localDevice.ID {
+		for _, s := range pendingSegments(time.Now()) {
+			if s.Start.Before(end) && s.End.After(start) {
+				s.DeviceID = localDevice.ID
+				segments = append(segments, s)
+			}
+		}
+	}
+	for _, s := range segments {
+		s.Category = opts.Categories[s.Label]
+	}
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].Start.Before(segments[j].Start)
+	})
+
+	if len(opts.BrowserClasses) > 0 {
+		browserEvents, err := q.GetBrowserEventsInRange(ctx, dbgen.GetBrowserEventsInRangeParams{
+			StartTime: start.Unix(),
+			EndTime:   end.Unix(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		segments = SplitByTabs(segments, browserEvents, opts.BrowserClasses)
+	}
+
+	segments = ApplyEdits(segments, edits)
+
+	result := make([]*Segment, 0, len(segments))
+	for _, s := range segments {
+		if s.Manual {
+			s.Remote = s.DeviceID != localDevice.ID
+		}
+		if s.Start.Before(start) {
+			s.Start = start
+		}
+		if s.End.After(end) {
+			s.End = end
+		}
+		if s.End.After(s.Start) {
+			result = append(result, s)
+		}
+	}
+
+	return result, nil
+}
+
+// ApplyEdits applies edits in the given order. An edit only changes the
+// segments of its own device. Manual entries take precedence over anything
+// recorded on that device in the same range so that time isn't counted twice.
+func ApplyEdits(segments []*Segment, edits []dbgen.EventEdit) []*Segment {
+	for _, edit := range edits {
+		editStart := time.Unix(edit.StartTime, 0)
+		editEnd := editStart.Add(time.Duration(edit.Duration) * time.Second)
+
+		next := make([]*Segment, 0, len(segments)+2)
+		for _, s := range segments {
+			if s.DeviceID != edit.DeviceID.String || !s.Start.Before(editEnd) || !s.End.After(editStart) {
+				next = append(next, s)
+				continue
+			}
+
+			if s.Start.Before(editStart) {
+				before := *s
+				before.End = editStart
+				next = append(next, &before)
+			}
+
+			if edit.Kind == EditKindRelabel || edit.Kind == EditKindSplit {
+				middle := *s
+				middle.Start = maxTime(s.Start, editStart)
+				middle.End = minTime(s.End, editEnd)
+				middle.Edited = true
+				if edit.Label.Valid && edit.Label.String != "" {
+					middle.Label = edit.Label.String
+				}
+				if edit.Category.Valid && edit.Category.String != "" {
+					middle.Category = edit.Category.String
+				}
+				next = append(next, &middle)
+			}
+
+			if s.End.After(editEnd) {
+				after := *s
+				after.Start = editEnd
+				next = append(next, &after)
+			}
+		}
+
+		if edit.Kind == EditKindAdd {
+			next = append(next, &Segment{
+				DeviceID: edit.DeviceID.String,
+				Start:    editStart,
+				End:      editEnd,
+				Label:    edit.Label.String,
+				Title:    edit.Note.String,
+				Category: edit.Category.String,
+				Manual:   true,
+			})
+		}
+
+		sort.SliceStable(next, func(i, j int) bool {
+			return next[i].Start.Before(next[j].Start)
+		})
+		segments = next
+	}
+
+	return segments
+}
+
+func toNullString(s string) sql.NullString {
+	s = strings.TrimSpace(s)
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,279 @@
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+func TestApplyEdits(t *testing.T) {
+	base := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	at := func(mins int) time.Time {
+		return base.Add(time.Duration(mins) * time.Minute)
+	}
+	edit := func(kind string, startMins, endMins int, label string) dbgen.EventEdit {
+		return dbgen.EventEdit{
+			Kind:      kind,
+			StartTime: at(startMins).Unix(),
+			Duration:  int64((endMins - startMins) * 60),
+			Label:     sql.NullString{String: label, Valid: label != ""},
+		}
+	}
+	recorded := func() []*Segment {
+		return []*Segment{
+			{EventID: 1, Start: at(0), End: at(60), Label: "firefox"},
+			{EventID: 2, Start: at(60), End: at(90), Label: "Alacritty"},
+		}
+	}
+
+	type want struct {
+		startMins int
+		endMins   int
+		label     string
+	}
+
+	tests := []struct {
+		name  string
+		edits []dbgen.EventEdit
+		want  []want
+	}{
+		{
+			name:  "no edits",
+			edits: nil,
+			want:  []want{{0, 60, "firefox"}, {60, 90, "Alacritty"}},
+		},
+		{
+			name:  "relabel part of an event",
+			edits: []dbgen.EventEdit{edit(EditKindRelabel, 15, 30, "meeting")},
+			want:  []want{{0, 15, "firefox"}, {15, 30, "meeting"}, {30, 60, "firefox"}, {60, 90, "Alacritty"}},
+		},
+		{
+			name:  "split an event",
+			edits: []dbgen.EventEdit{edit(EditKindSplit, 40, 60, "docs")},
+			want:  []want{{0, 40, "firefox"}, {40, 60, "docs"}, {60, 90, "Alacritty"}},
+		},
+		{
+			name:  "delete a range spanning two events",
+			edits: []dbgen.EventEdit{edit(EditKindDelete, 50, 70, "")},
+			want:  []want{{0, 50, "firefox"}, {70, 90, "Alacritty"}},
+		},
+		{
+			name:  "manual entry replaces recorded time",
+			edits: []dbgen.EventEdit{edit(EditKindAdd, 80, 120, "standup")},
+			want:  []want{{0, 60, "firefox"}, {60, 80, "Alacritty"}, {80, 120, "standup"}},
+		},
+		{
+			name: "later edits apply on top of earlier ones",
+			edits: []dbgen.EventEdit{
+				edit(EditKindAdd, 100, 130, "standup"),
+				edit(EditKindDelete, 110, 120, ""),
+			},
+			want: []want{{0, 60, "firefox"}, {60, 90, "Alacritty"}, {100, 110, "standup"}, {120, 130, "standup"}},
+		},
+	}
+
+	for _, tt := range tests {
+		got := ApplyEdits(recorded(), tt.edits)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %d segments, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+
+		for i, w := range tt.want {
+			if !got[i].Start.Equal(at(w.startMins)) || !got[i].End.Equal(at(w.endMins)) || got[i].Label != w.label {
+				t.Errorf(
+					"%s: segment %d: got=%v-%v %q, want=%v-%v %q",
+					tt.name, i,
+					got[i].Start.Format("15:04"), got[i].End.Format("15:04"), got[i].Label,
+					at(w.startMins).Format("15:04"), at(w.endMins).Format("15:04"), w.label,
+				)
+			}
+		}
+	}
+}
+
+func TestGetTimelineCategories(t *testing.T) {
+	windowChanges = nil
+	lastWindow = nil
+	t.Cleanup(func() {
+		windowChanges = nil
+		lastWindow = nil
+	})
+
+	ctx := context.Background()
+	q := dbgen.New(testutil.OpenDB(t))
+	base := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+
+	for i, class := range []string{"code", "firefox", "Alacritty"} {
+		err := q.InsertEvents(ctx, dbgen.InsertEventsParams{
+			StartTime:   base.Add(time.Duration(i) * time.Hour).Unix(),
+			WindowClass: class,
+			Duration:    60 * 60,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An edit overrides the category of the first half hour of code.
+	_, err := Relabel(ctx, q, EditParams{
+		Start:    base,
+		End:      base.Add(30 * time.Minute),
+		Category: "Meetings",
+		Source:   EditSourceWeb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{Categories: map[string]string{"code": "Work", "Alacritty": "Work"}}
+	stats, err := GetCategoryStats(ctx, q, base, base.Add(3*time.Hour), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]int64)
+	for _, s := range stats {
+		got[s.CategoryName] = s.DurationSecs
+	}
+	want := map[string]int64{"Work": 90 * 60, "Meetings": 30 * 60, UncategorizedName: 60 * 60}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGetTimelineScopesEditsToDevice(t *testing.T) {
+	windowChanges = nil
+	lastWindow = nil
+	t.Cleanup(func() {
+		windowChanges = nil
+		lastWindow = nil
+	})
+
+	ctx := context.Background()
+	q := dbgen.New(testutil.OpenDB(t))
+	base := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	at := func(mins int) time.Time {
+		return base.Add(time.Duration(mins) * time.Minute)
+	}
+
+	local, err := q.GetLocalDevice(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = q.InsertDevice(ctx, dbgen.InsertDeviceParams{ID: "laptop", Hostname: "laptop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both devices have Firefox open for the same hour, but only the local
+	// one switched tabs.
+	err = q.InsertEvents(ctx, dbgen.InsertEventsParams{
+		StartTime:   at(0).Unix(),
+		WindowClass: "firefox",
+		Duration:    60 * 60,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = q.InsertSyncedEvent(ctx, dbgen.InsertSyncedEventParams{
+		StartTime:   at(0).Unix(),
+		WindowClass: "firefox",
+		Duration:    60 * 60,
+		DeviceID:    sql.NullString{String: "laptop", Valid: true},
+		Seq:         sql.NullInt64{Int64: 1, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tab := range []struct {
+		mins   int
+		domain string
+	}{{0, "github.com"}, {30, "go.dev"}} {
+		err = q.InsertBrowserEvent(ctx, dbgen.InsertBrowserEventParams{
+			StartTime: at(tab.mins).Unix(),
+			Domain:    sql.NullString{String: tab.domain, Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := DeleteRange(ctx, q, EditParams{Start: at(0), End: at(15)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.DeviceID.String != local.ID {
+		t.Errorf("got an edit for device %q, want the local device %q", deleted.DeviceID.String, local.ID)
+	}
+	_, err = Relabel(ctx, q, EditParams{DeviceID: "laptop", Start: at(45), End: at(60), Label: "meeting"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = AddManualEntry(ctx, q, EditParams{Start: at(60), End: at(90), Label: "standup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Relabel(ctx, q, EditParams{DeviceID: "phone", Start: at(0), End: at(15), Label: "meeting"})
+	if !errors.Is(err, ErrInvalidEdit) {
+		t.Errorf("editing an unknown device: got %v, want %v", err, ErrInvalidEdit)
+	}
+
+	localSegments := []string{
+		"local 09:15-09:30 firefox github.com",
+		"local 09:30-10:00 firefox go.dev",
+		"local 10:00-10:30 standup ",
+	}
+	laptopSegments := []string{
+		"laptop 09:00-09:45 firefox ",
+		"laptop 09:45-10:00 meeting ",
+	}
+
+	tests := []struct {
+		deviceID string
+		want     []string
+	}{
+		{"", append(slices.Clone(localSegments), laptopSegments...)},
+		{local.ID, localSegments},
+		{"laptop", laptopSegments},
+	}
+
+	for _, tt := range tests {
+		opts := Options{DeviceID: tt.deviceID, BrowserClasses: []string{"firefox"}}
+		segments, err := GetTimeline(ctx, q, at(0), at(120), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, s := range segments {
+			device := s.DeviceID
+			if device == local.ID {
+				device = "local"
+			}
+			if s.Remote != (s.DeviceID != local.ID) {
+				t.Errorf("device %q: segment of %q has Remote %v", tt.deviceID, s.DeviceID, s.Remote)
+			}
+
+			got = append(got, fmt.Sprintf(
+				"%s %s-%s %s %s",
+				device, s.Start.Format("15:04"), s.End.Format("15:04"), s.Label, s.Domain,
+			))
+		}
+
+		slices.Sort(got)
+		slices.Sort(tt.want)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("device %q:\ngot  %q\nwant %q", tt.deviceID, got, tt.want)
+		}
+	}
+}
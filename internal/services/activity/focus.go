@@ -0,0 +1,67 @@
+package activity
+
+import "time"
+
+// MinFocusSession is how long a stretch of using a single program has to last
+// to count as focus time.
+const MinFocusSession = 25 * time.Minute
+
+// maxFocusInterruption is how long a stretch can be interrupted, by another
+// program or by not using the computer, before it ends. Glancing at a chat
+// window doesn't end it.
+const maxFocusInterruption = 2 * time.Minute
+
+// FocusStats describe how long the stretches of using a single program were.
+type FocusStats struct {
+	// FocusSecs is the time spent in sessions of at least MinFocusSession.
+	FocusSecs          int64
+	FocusSessions      int
+	LongestSessionSecs int64
+	ContextSwitches    int
+}
+
+// GetFocusStats finds the stretches of using a single program in segments,
+// which are sorted by their start.
+func GetFocusStats(segments []*Segment) FocusStats {
+	var stats FocusStats
+	var session *Segment
+	var prevLabel string
+
+	endSession := func() {
+		if session == nil {
+			return
+		}
+
+		secs := session.DurationSecs()
+		stats.LongestSessionSecs = max(stats.LongestSessionSecs, secs)
+		if secs >= int64(MinFocusSession.Seconds()) {
+			stats.FocusSecs += secs
+			stats.FocusSessions++
+		}
+	}
+
+	for i, s := range segments {
+		if i > 0 && s.Label != prevLabel {
+			stats.ContextSwitches++
+		}
+		prevLabel = s.Label
+
+		if session != nil {
+			if s.Label == session.Label && s.Start.Sub(session.End) <= maxFocusInterruption {
+				if s.End.After(session.End) {
+					session.End = s.End
+				}
+				continue
+			}
+			if s.Label != session.Label && s.End.Sub(session.End) <= maxFocusInterruption {
+				continue
+			}
+		}
+
+		endSession()
+		session = &Segment{Label: s.Label, Start: s.Start, End: s.End}
+	}
+	endSession()
+
+	return stats
+}
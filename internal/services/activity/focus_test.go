@@ -0,0 +1,42 @@
+package activity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetFocusStats(t *testing.T) {
+	base := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	segment := func(label string, startMin int, endMin int) *Segment {
+		return &Segment{
+			Label: label,
+			Start: base.Add(time.Duration(startMin) * time.Minute),
+			End:   base.Add(time.Duration(endMin) * time.Minute),
+		}
+	}
+
+	segments := []*Segment{
+		// 40 minutes in Code with a quick look at Slack in between.
+		segment("Code", 0, 20),
+		segment("Slack", 20, 21),
+		segment("Code", 21, 40),
+		// A longer break ends the session.
+		segment("firefox", 40, 50),
+		segment("Code", 50, 60),
+	}
+
+	got := GetFocusStats(segments)
+	want := FocusStats{
+		FocusSecs:          40 * 60,
+		FocusSessions:      1,
+		LongestSessionSecs: 40 * 60,
+		ContextSwitches:    4,
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if got := GetFocusStats(nil); got != (FocusStats{}) {
+		t.Errorf("got %+v for no segments", got)
+	}
+}
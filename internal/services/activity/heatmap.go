@@ -0,0 +1,76 @@
+package activity
+
+import "time"
+
+// HeatmapFilter limits the heatmaps to a program or a category. Empty fields
+// match everything.
+type HeatmapFilter struct {
+	Program  string
+	Category string
+}
+
+func (f HeatmapFilter) matches(s *Segment) bool {
+	if f.Program != "" && s.Label != f.Program {
+		return false
+	}
+
+	category := s.Category
+	if category == "" {
+		category = UncategorizedName
+	}
+
+	return f.Category == "" || category == f.Category
+}
+
+// HourlyDurations splits the time spent in the segments that match filter
+// into the hours it was spent in. The keys are the starts of the hours in the
+// location of the segments.
+func HourlyDurations(segments []*Segment, filter HeatmapFilter) map[time.Time]time.Duration {
+	result := make(map[time.Time]time.Duration)
+
+	for _, s := range segments {
+		if !filter.matches(s) {
+			continue
+		}
+
+		for t := s.Start; t.Before(s.End); {
+			// Truncate would round in UTC, which is wrong for time zones
+			// whose offset isn't a whole number of hours.
+			hour := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+			next := hour.Add(time.Hour)
+			if s.End.Before(next) {
+				next = s.End
+			}
+
+			result[hour] += next.Sub(t)
+			t = next
+		}
+	}
+
+	return result
+}
+
+// DailyDurations sums up hourly durations per day in seconds. The keys are
+// dates (format: "2006-01-02"). Days start at dayStartHour, so the hours after
+// midnight count towards the day before.
+func DailyDurations(hourly map[time.Time]time.Duration, dayStartHour int) map[string]int64 {
+	result := make(map[string]int64)
+	for hour, d := range hourly {
+		day := hour.Add(-time.Duration(dayStartHour) * time.Hour)
+		result[day.Format("2006-01-02")] += int64(d.Seconds())
+	}
+
+	return result
+}
+
+// WeekdayHourDurations sums up hourly durations per weekday and hour of the
+// day in seconds. The weekdays start on Monday.
+func WeekdayHourDurations(hourly map[time.Time]time.Duration) [7][24]int64 {
+	var result [7][24]int64
+	for hour, d := range hourly {
+		weekday := (int(hour.Weekday()) + 6) % 7
+		result[weekday][hour.Hour()] += int64(d.Seconds())
+	}
+
+	return result
+}
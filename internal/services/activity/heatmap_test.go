@@ -0,0 +1,50 @@
+package activity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHourlyDurationsSplitsSegments(t *testing.T) {
+	// A Monday.
+	base := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	segments := []*Segment{
+		{Label: "Code", Category: "Work", Start: base.Add(2*time.Hour + 30*time.Minute), End: base.Add(4*time.Hour + 15*time.Minute)},
+		{Label: "firefox", Start: base.Add(10 * time.Hour), End: base.Add(10*time.Hour + 20*time.Minute)},
+	}
+
+	hourly := HourlyDurations(segments, HeatmapFilter{})
+	want := map[int]time.Duration{
+		2:  30 * time.Minute,
+		3:  time.Hour,
+		4:  15 * time.Minute,
+		10: 20 * time.Minute,
+	}
+	if len(hourly) != len(want) {
+		t.Fatalf("got %v", hourly)
+	}
+	for h, d := range want {
+		if got := hourly[base.Add(time.Duration(h)*time.Hour)]; got != d {
+			t.Errorf("hour %d: got %v, want %v", h, got, d)
+		}
+	}
+
+	grid := WeekdayHourDurations(hourly)
+	if grid[0][3] != 3600 || grid[0][10] != 1200 {
+		t.Errorf("got Monday %v", grid[0])
+	}
+
+	// With days starting at 4, the hours before count towards Sunday.
+	daily := DailyDurations(hourly, 4)
+	if daily["2025-06-01"] != 90*60 || daily["2025-06-02"] != 35*60 {
+		t.Errorf("got %v", daily)
+	}
+
+	filtered := HourlyDurations(segments, HeatmapFilter{Category: UncategorizedName})
+	if len(filtered) != 1 || filtered[base.Add(10*time.Hour)] != 20*time.Minute {
+		t.Errorf("got %v for the uncategorized time", filtered)
+	}
+	if filtered = HourlyDurations(segments, HeatmapFilter{Program: "Code", Category: "Work"}); len(filtered) != 3 {
+		t.Errorf("got %v for Code", filtered)
+	}
+}
@@ -0,0 +1,185 @@
+package activity
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+)
+
+// Events are only written to the database every SaveInterval, so every event
+// is also appended to a journal file as soon as it's closed. The journal is
+// emptied after each successful save and replayed on startup, which means
+// that a crash loses at most the event that was still open.
+type eventJournal struct {
+	file *os.File
+	enc  *json.Encoder
+}
+
+// journalEntry is the on-disk format of a journaled event. It's kept separate
+// from WindowChangeEvent so that the format doesn't change by accident.
+type journalEntry struct {
+	Start       int64  `json:"start"`
+	Duration    uint32 `json:"duration"`
+	WindowID    string `json:"window_id,omitempty"`
+	WindowClass string `json:"window_class"`
+	WindowName  string `json:"window_name,omitempty"`
+	PID         int    `json:"pid,omitempty"`
+	ExePath     string `json:"exe_path,omitempty"`
+	Cmdline     string `json:"cmdline,omitempty"`
+}
+
+// journal is guarded by mu. It's nil if journaling is disabled.
+var journal *eventJournal
+
+// openJournal saves the events left in the journal at path by a previous run
+// that didn't shut down cleanly and then opens the journal for appending.
+func openJournal(db *sql.DB, path string) error {
+	events, err := readJournal(path)
+	if err != nil {
+		return err
+	}
+
+	events, err = unsavedEvents(context.Background(), dbgen.New(db), events)
+	if err != nil {
+		return fmt.Errorf("checking the journal against the saved events: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("opening the journal: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	journal = &eventJournal{file: file, enc: json.NewEncoder(file)}
+
+	if len(events) > 0 {
+		slog.Info("replaying the journal", "path", path, "events", len(events))
+		windowChanges = append(events, windowChanges...)
+		if err := save(db, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	// Saving empties the journal too, but that doesn't happen if every event
+	// in it had already been saved.
+	return journal.truncate()
+}
+
+func closeJournal() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if journal == nil {
+		return
+	}
+
+	if err := journal.file.Close(); err != nil {
+		slog.Error("failed to close the journal", "err", err)
+	}
+	journal = nil
+}
+
+func readJournal(path string) ([]*WindowChangeEvent, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("opening the journal: %v", err)
+	}
+	defer file.Close()
+
+	var events []*WindowChangeEvent
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// The last line is cut off if the crash happened while it was
+			// being written.
+			slog.Warn("skipping a malformed journal entry", "path", path, "line", lineNumber, "err", err)
+			continue
+		}
+
+		events = append(events, &WindowChangeEvent{
+			StartTimestamp: time.Unix(entry.Start, 0),
+			WindowID:       entry.WindowID,
+			WindowClass:    entry.WindowClass,
+			WindowName:     entry.WindowName,
+			Process: Process{
+				PID:     entry.PID,
+				ExePath: entry.ExePath,
+				Cmdline: entry.Cmdline,
+			},
+			DurationSecs: entry.Duration,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading the journal: %v", err)
+	}
+
+	return events, nil
+}
+
+// unsavedEvents drops the events that are already in the database, which is
+// the case if the process stopped between a save and emptying the journal.
+func unsavedEvents(ctx context.Context, q *dbgen.Queries, events []*WindowChangeEvent) ([]*WindowChangeEvent, error) {
+	result := make([]*WindowChangeEvent, 0, len(events))
+	for _, e := range events {
+		count, err := q.CountEventsByStart(ctx, dbgen.CountEventsByStartParams{
+			StartTime:   e.StartTimestamp.Unix(),
+			WindowClass: e.WindowClass,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if count == 0 {
+			result = append(result, e)
+		}
+	}
+
+	return result, nil
+}
+
+func (j *eventJournal) append(e *WindowChangeEvent) error {
+	err := j.enc.Encode(journalEntry{
+		Start:       e.StartTimestamp.Unix(),
+		Duration:    e.DurationSecs,
+		WindowID:    e.WindowID,
+		WindowClass: e.WindowClass,
+		WindowName:  e.WindowName,
+		PID:         e.Process.PID,
+		ExePath:     e.Process.ExePath,
+		Cmdline:     e.Process.Cmdline,
+	})
+	if err != nil {
+		return err
+	}
+
+	return j.file.Sync()
+}
+
+func (j *eventJournal) truncate() error {
+	// The file is opened with O_APPEND, so writes continue at the new end.
+	return j.file.Truncate(0)
+}
+
+// addWindowChange queues a closed event for the next save and journals it.
+// The caller must hold mu.
+func addWindowChange(e *WindowChangeEvent) {
+	windowChanges = append(windowChanges, e)
+
+	if journal != nil {
+		if err := journal.append(e); err != nil {
+			slog.Error("failed to journal the event", "err", err)
+		}
+	}
+}
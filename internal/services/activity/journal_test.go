@@ -0,0 +1,93 @@
+package activity
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+func TestJournalReplay(t *testing.T) {
+	windowChanges = nil
+	lastWindow = nil
+	t.Cleanup(func() {
+		closeJournal()
+		windowChanges = nil
+		lastWindow = nil
+	})
+
+	ctx := context.Background()
+	db := testutil.OpenDB(t)
+	q := dbgen.New(db)
+	base := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+
+	// The first event was saved right before the crash, but the journal
+	// wasn't emptied yet.
+	err := q.InsertEvents(ctx, dbgen.InsertEventsParams{
+		StartTime:   base.Unix(),
+		WindowClass: "firefox",
+		Duration:    60,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "telltime.journal")
+	lines := []string{
+		`{"start":1748854800,"duration":60,"window_class":"firefox"}`,
+		`{"start":1748854860,"duration":30,"window_class":"Alacritty","pid":42}`,
+		`{"start":1748854890,"duration":90,"window_class":"Emacs"}`,
+		`{"start":1748854980,"dura`,
+	}
+	if err = os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = openJournal(db, path); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := q.GetEventsInRange(ctx, dbgen.GetEventsInRangeParams{
+		StartTime: base.Unix(),
+		EndTime:   base.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantClasses := []string{"firefox", "Alacritty", "Emacs"}
+	if len(events) != len(wantClasses) {
+		t.Fatalf("got %d events, want %d", len(events), len(wantClasses))
+	}
+	for i, w := range wantClasses {
+		if events[i].WindowClass != w {
+			t.Errorf("event %d: got=%q, want=%q", i, events[i].WindowClass, w)
+		}
+	}
+	if events[1].Pid.Int64 != 42 {
+		t.Errorf("got pid=%d, want=42", events[1].Pid.Int64)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Fatalf("expected the journal to be empty after replaying it (err=%v)", err)
+	}
+
+	// Closed events are journaled right away.
+	config := &conf.Config{}
+	recordWindow(base.Add(time.Hour), config, WindowInfo{WindowID: "1", WindowClass: "Code"})
+	recordWindow(base.Add(time.Hour+time.Minute), config, WindowInfo{WindowID: "2", WindowClass: "firefox"})
+
+	journaled, err := readJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journaled) != 1 || journaled[0].WindowClass != "Code" || journaled[0].DurationSecs != 60 {
+		t.Errorf("got journaled=%+v, want one 60s event for Code", journaled)
+	}
+}
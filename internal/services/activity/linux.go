@@ -1,76 +1,282 @@
 package activity
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
-	"os/signal"
+	"path/filepath"
+	"slices"
 	"strconv"
-	"syscall"
+	"strings"
 	"time"
 
+	"github.com/BurntSushi/xgb/xproto"
 	"github.com/BurntSushi/xgbutil"
 	"github.com/BurntSushi/xgbutil/ewmh"
 	"github.com/BurntSushi/xgbutil/icccm"
+	"github.com/BurntSushi/xgbutil/xevent"
+	"github.com/BurntSushi/xgbutil/xprop"
+	"github.com/BurntSushi/xgbutil/xwindow"
 	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/godbus/dbus/v5"
 )
 
 type LinuxWindowCheckResult struct {
+	XWindowID       xproto.Window
 	ParsedXWindowID string
-	XWindowClass string
-	XWindowName string
+	XWindowClass    string
+	XWindowName     string
+	Process         Process
+	Desktop         bool
 }
 
-func initLinux(db *sql.DB, config *conf.Config) {
+var errActiveWindowUnsupported = errors.New("the window manager doesn't support _NET_ACTIVE_WINDOW")
+
+func runLinux(ctx context.Context, db *sql.DB, config *conf.Config) error {
 	xUtil, err := xgbutil.NewConn()
 	if err != nil {
-		slog.Error("failed to establish a connection with X", "err", err)
+		return fmt.Errorf("connecting to X: %v", err)
 	}
 	defer xUtil.Conn().Close()
 
+	onChange := func(result LinuxWindowCheckResult) {
+		updateCurrentActivity(config, WindowInfo{
+			WindowID:    result.ParsedXWindowID,
+			WindowClass: result.XWindowClass,
+			WindowName:  result.XWindowName,
+			Process:     result.Process,
+			Desktop:     result.Desktop,
+		})
+	}
+
+	// Nil channels block forever, so only one of the two window sources below
+	// ends up being selected in the loop.
+	var windowCheckC, healthCheckC <-chan time.Time
+	var pingBefore, pingAfter, pingQuit chan struct{}
+
 	windowCheckTicker := time.NewTicker(
 		time.Duration(config.WindowCheckInterval) * time.Second,
 	)
+	defer windowCheckTicker.Stop()
+
+	if err := watchLinuxWindows(xUtil, config, onChange); err != nil {
+		slog.Warn("falling back to polling for window changes", "err", err)
+		setBackend(BackendX11Polling)
+		windowCheckC = windowCheckTicker.C
+	} else {
+		setBackend(BackendX11Events)
+		pingBefore, pingAfter, pingQuit = xevent.MainPing(xUtil)
+
+		// Events only arrive when something changes, so the connection is
+		// checked on every tick to notice when X becomes unreachable.
+		healthCheckC = windowCheckTicker.C
+	}
+
 	saveTicker := time.NewTicker(
 		time.Duration(config.SaveInterval) * time.Second,
 	)
-
-	defer windowCheckTicker.Stop()
 	defer saveTicker.Stop()
 
-	signalC := make(chan os.Signal, 1)
-	signal.Notify(signalC, syscall.SIGINT, syscall.SIGTERM)
+	// The tracker keeps working without logind, and a suspend is still
+	// noticed through the clock jump once the system wakes up.
+	var sessionC <-chan sessionEvent
+	if conn, err := dbus.ConnectSystemBus(); err != nil {
+		slog.Warn("not watching for sleep and screen locks", "err", err)
+	} else {
+		defer conn.Close()
+		if sessionC, err = watchLogind(ctx, conn); err != nil {
+			slog.Warn("not watching for sleep and screen locks", "err", err)
+		}
+	}
+
+	var mediaConn *dbus.Conn
+	if config.DetectMediaPlayback {
+		if mediaConn, err = connectSessionBus(); err != nil {
+			slog.Warn("not checking for media playback", "err", err)
+		} else {
+			defer mediaConn.Close()
+		}
+	}
+
+	lastCheck := time.Now()
 
-loop:
 	for {
 		select {
-		case <-windowCheckTicker.C:
-			result := checkLinuxWindow(xUtil, config)
-			updateCurrentActivity(
-				result.ParsedXWindowID,
-				result.XWindowClass,
-				result.XWindowName,
-			)
+		case <-windowCheckC:
+			now := time.Now()
+			handleClockJump(lastCheck, now, config)
+			lastCheck = now
+
+			onChange(checkLinuxWindow(xUtil, config))
+
+			if mediaConn != nil {
+				if err := checkMediaPlayback(ctx, mediaConn); err != nil {
+					slog.Debug("failed to check for media playback", "err", err)
+				}
+			}
+		case <-healthCheckC:
+			now := time.Now()
+			if handleClockJump(lastCheck, now, config) {
+				onChange(checkLinuxWindow(xUtil, config))
+			}
+			lastCheck = now
+
+			if mediaConn != nil {
+				if err := checkMediaPlayback(ctx, mediaConn); err != nil {
+					slog.Debug("failed to check for media playback", "err", err)
+				}
+			}
+
+			if _, err := ewmh.ActiveWindowGet(xUtil); err != nil {
+				slog.Error("failed to get the current active window", "err", err)
+				recordTickError(err)
+			} else {
+				recordWindowRead()
+			}
+		case <-pingBefore:
+			// The callbacks registered in watchLinuxWindows run in the
+			// xevent loop. Wait for them to finish before doing anything else.
+			<-pingAfter
+		case <-pingQuit:
+			slog.Error("the X event loop has stopped; falling back to polling for window changes")
+			setBackend(BackendX11Polling)
+			pingBefore, pingAfter, pingQuit = nil, nil, nil
+			windowCheckC, healthCheckC = windowCheckTicker.C, nil
+		case event, ok := <-sessionC:
+			if !ok {
+				slog.Warn("lost the connection to the system bus")
+				sessionC = nil
+				continue
+			}
+
+			if handleSessionEvent(event, config) {
+				onChange(checkLinuxWindow(xUtil, config))
+			}
+		case newConfig := <-configUpdates:
+			applyConfig(config, newConfig, windowCheckTicker, saveTicker)
+
+			// The focused window might have to be recorded differently now,
+			// e.g. because its class is excluded.
+			onChange(checkLinuxWindow(xUtil, config))
 		case <-saveTicker.C:
 			Save(db)
-		case <-signalC:
-			handleGracefulShutdown(db)
-			break loop
+		case <-ctx.Done():
+			if pingQuit != nil {
+				xevent.Quit(xUtil)
+			}
+
+			return handleGracefulShutdown(db, config)
 		}
 	}
+}
 
-	os.Exit(0)
+// watchLinuxWindows subscribes to PropertyNotify events for _NET_ACTIVE_WINDOW
+// on the root window and for _NET_WM_NAME and WM_NAME on the active window.
+// onChange is called from the xevent loop with the active window every time
+// one of these properties changes; title changes are only reported while
+// titles are recorded, so turning them on doesn't need a restart. An error is
+// returned if the window manager doesn't support _NET_ACTIVE_WINDOW, in which
+// case the caller should fall back to polling.
+func watchLinuxWindows(
+	xUtil *xgbutil.XUtil,
+	config *conf.Config,
+	onChange func(LinuxWindowCheckResult),
+) error {
+	supported, err := ewmh.SupportedGet(xUtil)
+	if err != nil {
+		return fmt.Errorf("reading _NET_SUPPORTED: %v", err)
+	}
+	if !slices.Contains(supported, "_NET_ACTIVE_WINDOW") {
+		return errActiveWindowUnsupported
+	}
+
+	activeWindowAtom, err := xprop.Atm(xUtil, "_NET_ACTIVE_WINDOW")
+	if err != nil {
+		return err
+	}
+	netWmNameAtom, err := xprop.Atm(xUtil, "_NET_WM_NAME")
+	if err != nil {
+		return err
+	}
+
+	root := xUtil.RootWin()
+	if err := xwindow.New(xUtil, root).Listen(xproto.EventMaskPropertyChange); err != nil {
+		return fmt.Errorf("listening for property changes on the root window: %v", err)
+	}
+
+	// watchedWindow is never the root window: detaching it would also remove
+	// the callback for _NET_ACTIVE_WINDOW.
+	var watchedWindow xproto.Window
+	watchTitle := func(xWindowID xproto.Window) {
+		if xWindowID == watchedWindow {
+			return
+		}
+
+		if watchedWindow != 0 {
+			xevent.Detach(xUtil, watchedWindow)
+			watchedWindow = 0
+		}
+		if xWindowID == 0 || xWindowID == root {
+			return
+		}
+		watchedWindow = xWindowID
+
+		// The window might already be gone by the time we get here.
+		err := xwindow.New(xUtil, xWindowID).Listen(xproto.EventMaskPropertyChange)
+		if err != nil {
+			slog.Debug("failed to listen for title changes", "xWindowID", xWindowID, "err", err)
+			return
+		}
+
+		xevent.PropertyNotifyFun(func(xu *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
+			if !config.RecordWindowTitles {
+				return
+			}
+			if ev.Atom == netWmNameAtom || ev.Atom == xproto.AtomWmName {
+				onChange(checkLinuxWindow(xu, config))
+			}
+		}).Connect(xUtil, xWindowID)
+	}
+
+	xevent.PropertyNotifyFun(func(xu *xgbutil.XUtil, ev xevent.PropertyNotifyEvent) {
+		if ev.Atom != activeWindowAtom {
+			return
+		}
+
+		result := checkLinuxWindow(xu, config)
+		watchTitle(result.XWindowID)
+		onChange(result)
+	}).Connect(xUtil, root)
+
+	result := checkLinuxWindow(xUtil, config)
+	watchTitle(result.XWindowID)
+	onChange(result)
+
+	return nil
 }
 
 func checkLinuxWindow(xUtil *xgbutil.XUtil, config *conf.Config) LinuxWindowCheckResult {
 	xWindowID, err := ewmh.ActiveWindowGet(xUtil)
 	if err != nil {
 		slog.Error("failed to get the current active window", "err", err)
+		recordTickError(err)
+	} else {
+		recordWindowRead()
+
+		// With no window focused, the active window is either unset or the
+		// root window, depending on the window manager.
+		if xWindowID == 0 || xWindowID == xUtil.RootWin() {
+			return LinuxWindowCheckResult{XWindowID: xWindowID, Desktop: true}
+		}
 	}
 
 	xWindowClassResult, err := icccm.WmClassGet(xUtil, xWindowID)
 	if err != nil {
 		slog.Error("failed to get window class", "xWindowID", xWindowID, "err", err)
+		recordTickError(err)
 	}
 	if xWindowClassResult != nil && xWindowClassResult.Class != xWindowClassResult.Instance {
 		slog.Debug("window class & instance differ", "xWindowClass.Class", xWindowClassResult.Class, "xWindowClass.Instance", xWindowClassResult.Instance)
@@ -90,6 +296,20 @@ func checkLinuxWindow(xUtil *xgbutil.XUtil, config *conf.Config) LinuxWindowChec
 		}
 	}
 
+	var process Process
+	pid, err := ewmh.WmPidGet(xUtil, xWindowID)
+	if err != nil {
+		slog.Debug("failed to get _NET_WM_PID", "xWindowID", xWindowID, "err", err)
+	} else {
+		process = readLinuxProcess(int(pid), config)
+	}
+
+	// File managers like xfdesktop draw the desktop in a window of their own.
+	windowTypes, err := ewmh.WmWindowTypeGet(xUtil, xWindowID)
+	if err != nil {
+		slog.Debug("failed to get _NET_WM_WINDOW_TYPE", "xWindowID", xWindowID, "err", err)
+	}
+
 	parsedXWindowID := strconv.FormatUint(uint64(xWindowID), 10)
 
 	var xWindowClass string
@@ -98,8 +318,35 @@ func checkLinuxWindow(xUtil *xgbutil.XUtil, config *conf.Config) LinuxWindowChec
 	}
 
 	return LinuxWindowCheckResult{
+		XWindowID:       xWindowID,
 		ParsedXWindowID: parsedXWindowID,
-		XWindowClass: xWindowClass,
-		XWindowName: xWindowName,
+		XWindowClass:    xWindowClass,
+		XWindowName:     xWindowName,
+		Process:         process,
+		Desktop:         slices.Contains(windowTypes, "_NET_WM_WINDOW_TYPE_DESKTOP"),
 	}
 }
+
+// readLinuxProcess resolves the executable and the command line of pid
+// through /proc. Command lines can contain file names and other sensitive
+// arguments, so they're only read if the user opted in.
+func readLinuxProcess(pid int, config *conf.Config) Process {
+	process := Process{PID: pid}
+	procDir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	exePath, err := os.Readlink(filepath.Join(procDir, "exe"))
+	if err != nil {
+		slog.Debug("failed to resolve the executable", "pid", pid, "err", err)
+	}
+	process.ExePath = exePath
+
+	if config.RecordCommandLines {
+		cmdline, err := os.ReadFile(filepath.Join(procDir, "cmdline"))
+		if err != nil {
+			slog.Debug("failed to read the command line", "pid", pid, "err", err)
+		}
+		process.Cmdline = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	}
+
+	return process
+}
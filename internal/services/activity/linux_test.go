@@ -0,0 +1,311 @@
+//go:build linux
+
+package activity
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil"
+	"github.com/BurntSushi/xgbutil/ewmh"
+	"github.com/BurntSushi/xgbutil/icccm"
+	"github.com/BurntSushi/xgbutil/xevent"
+	"github.com/BurntSushi/xgbutil/xwindow"
+	"github.com/bnuredini/telltime/internal/conf"
+)
+
+// startXvfb starts a headless X server and returns its display name. The test
+// is skipped if Xvfb isn't installed.
+func startXvfb(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("Xvfb"); err != nil {
+		t.Skip("Xvfb is not installed")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// With -displayfd, Xvfb picks a free display and writes its number to
+	// the given file descriptor once it's ready to accept connections.
+	cmd := exec.Command("Xvfb", "-displayfd", "3", "-nolisten", "tcp")
+	cmd.ExtraFiles = []*os.File{w}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read the display number: %v", err)
+	}
+
+	return ":" + strings.TrimSpace(line)
+}
+
+// scriptedWM plays the part of a window manager: it advertises EWMH support,
+// creates client windows and moves the focus between them.
+type scriptedWM struct {
+	t  *testing.T
+	xu *xgbutil.XUtil
+}
+
+func (wm *scriptedWM) createWindow(class string, name string) xproto.Window {
+	win, err := xwindow.Generate(wm.xu)
+	if err != nil {
+		wm.t.Fatal(err)
+	}
+	win.Create(wm.xu.RootWin(), 0, 0, 100, 100, 0)
+
+	err = icccm.WmClassSet(wm.xu, win.Id, &icccm.WmClass{Instance: class, Class: class})
+	if err != nil {
+		wm.t.Fatal(err)
+	}
+	if err = ewmh.WmNameSet(wm.xu, win.Id, name); err != nil {
+		wm.t.Fatal(err)
+	}
+
+	return win.Id
+}
+
+func (wm *scriptedWM) focus(win xproto.Window) {
+	if err := ewmh.ActiveWindowSet(wm.xu, win); err != nil {
+		wm.t.Fatal(err)
+	}
+}
+
+// runEventLoop runs the xevent loop of xu the way runLinux does. The returned
+// function runs f between two events, which is when the tracker applies new
+// settings.
+func runEventLoop(t *testing.T, xu *xgbutil.XUtil) func(f func()) {
+	pingBefore, pingAfter, pingQuit := xevent.MainPing(xu)
+	calls := make(chan func())
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-pingBefore:
+				<-pingAfter
+			case f := <-calls:
+				f()
+				done <- struct{}{}
+			case <-pingQuit:
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() { xevent.Quit(xu) })
+
+	return func(f func()) {
+		calls <- f
+		<-done
+	}
+}
+
+// expectWindow waits for the next result and compares it with the class and
+// the name of the window that should be active.
+func expectWindow(t *testing.T, results <-chan LinuxWindowCheckResult, class string, name string) {
+	t.Helper()
+
+	select {
+	case result := <-results:
+		if result.XWindowClass != class || result.XWindowName != name {
+			t.Errorf("got=%q %q, want=%q %q", result.XWindowClass, result.XWindowName, class, name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %q %q", class, name)
+	}
+}
+
+func TestWatchLinuxWindows(t *testing.T) {
+	display := startXvfb(t)
+
+	wmXU, err := xgbutil.NewConnDisplay(display)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wmXU.Conn().Close()
+
+	wm := &scriptedWM{t: t, xu: wmXU}
+	if err = ewmh.SupportedSet(wmXU, []string{"_NET_ACTIVE_WINDOW", "_NET_WM_NAME"}); err != nil {
+		t.Fatal(err)
+	}
+
+	editor := wm.createWindow("Emacs", "main.go")
+	browser := wm.createWindow("firefox", "Mozilla Firefox")
+	wm.focus(editor)
+
+	trackerXU, err := xgbutil.NewConnDisplay(display)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer trackerXU.Conn().Close()
+
+	results := make(chan LinuxWindowCheckResult, 16)
+	config := &conf.Config{RecordWindowTitles: true}
+	err = watchLinuxWindows(trackerXU, config, func(result LinuxWindowCheckResult) {
+		results <- result
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go xevent.Main(trackerXU)
+	defer xevent.Quit(trackerXU)
+
+	expect := func(class string, name string) {
+		t.Helper()
+		expectWindow(t, results, class, name)
+	}
+
+	expect("Emacs", "main.go")
+
+	wm.focus(browser)
+	expect("firefox", "Mozilla Firefox")
+
+	if err = ewmh.WmNameSet(wmXU, browser, "Pull requests"); err != nil {
+		t.Fatal(err)
+	}
+	expect("firefox", "Pull requests")
+
+	// Title changes in windows that aren't focused anymore are ignored.
+	wm.focus(editor)
+	expect("Emacs", "main.go")
+	if err = ewmh.WmNameSet(wmXU, browser, "Issues"); err != nil {
+		t.Fatal(err)
+	}
+	if err = ewmh.WmNameSet(wmXU, editor, "routes.go"); err != nil {
+		t.Fatal(err)
+	}
+	expect("Emacs", "routes.go")
+}
+
+func TestWatchLinuxWindowsUnsupported(t *testing.T) {
+	display := startXvfb(t)
+
+	xu, err := xgbutil.NewConnDisplay(display)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xu.Conn().Close()
+
+	err = watchLinuxWindows(xu, &conf.Config{}, func(LinuxWindowCheckResult) {})
+	if err == nil {
+		t.Fatal("expected an error when the window manager doesn't advertise _NET_ACTIVE_WINDOW")
+	}
+}
+
+// Some window managers report the root window as active while the desktop is
+// focused, which must not stop the tracker from noticing later focus changes.
+func TestWatchLinuxWindowsAfterDesktop(t *testing.T) {
+	display := startXvfb(t)
+
+	wmXU, err := xgbutil.NewConnDisplay(display)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wmXU.Conn().Close()
+
+	wm := &scriptedWM{t: t, xu: wmXU}
+	if err = ewmh.SupportedSet(wmXU, []string{"_NET_ACTIVE_WINDOW", "_NET_WM_NAME"}); err != nil {
+		t.Fatal(err)
+	}
+
+	editor := wm.createWindow("Emacs", "main.go")
+	browser := wm.createWindow("firefox", "Mozilla Firefox")
+	wm.focus(editor)
+
+	trackerXU, err := xgbutil.NewConnDisplay(display)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer trackerXU.Conn().Close()
+
+	results := make(chan LinuxWindowCheckResult, 16)
+	config := &conf.Config{RecordWindowTitles: true}
+	err = watchLinuxWindows(trackerXU, config, func(result LinuxWindowCheckResult) {
+		results <- result
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runEventLoop(t, trackerXU)
+
+	expectWindow(t, results, "Emacs", "main.go")
+
+	wm.focus(wmXU.RootWin())
+	expectWindow(t, results, "", "")
+
+	wm.focus(browser)
+	expectWindow(t, results, "firefox", "Mozilla Firefox")
+
+	wm.focus(editor)
+	expectWindow(t, results, "Emacs", "main.go")
+}
+
+func TestWatchLinuxWindowsTitlesTurnedOn(t *testing.T) {
+	display := startXvfb(t)
+
+	wmXU, err := xgbutil.NewConnDisplay(display)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wmXU.Conn().Close()
+
+	wm := &scriptedWM{t: t, xu: wmXU}
+	if err = ewmh.SupportedSet(wmXU, []string{"_NET_ACTIVE_WINDOW", "_NET_WM_NAME"}); err != nil {
+		t.Fatal(err)
+	}
+
+	editor := wm.createWindow("Emacs", "main.go")
+	browser := wm.createWindow("firefox", "Mozilla Firefox")
+	wm.focus(editor)
+
+	trackerXU, err := xgbutil.NewConnDisplay(display)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer trackerXU.Conn().Close()
+
+	results := make(chan LinuxWindowCheckResult, 16)
+	config := &conf.Config{}
+	err = watchLinuxWindows(trackerXU, config, func(result LinuxWindowCheckResult) {
+		results <- result
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	between := runEventLoop(t, trackerXU)
+
+	expectWindow(t, results, "Emacs", "")
+
+	// Title changes aren't reported while titles are off. Events are handled
+	// in order, so the focus changes show that the title change was skipped.
+	if err = ewmh.WmNameSet(wmXU, editor, "routes.go"); err != nil {
+		t.Fatal(err)
+	}
+	wm.focus(browser)
+	expectWindow(t, results, "firefox", "")
+	wm.focus(editor)
+	expectWindow(t, results, "Emacs", "")
+
+	between(func() { config.RecordWindowTitles = true })
+
+	if err = ewmh.WmNameSet(wmXU, editor, "handlers.go"); err != nil {
+		t.Fatal(err)
+	}
+	expectWindow(t, results, "Emacs", "handlers.go")
+}
@@ -0,0 +1,40 @@
+package activity
+
+import "sync"
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   = make(map[chan struct{}]struct{})
+)
+
+// Subscribe returns a channel that receives a value whenever the tracker
+// observes a window change. Notifications are coalesced, so a slow reader
+// only sees that something changed since it last looked. The returned
+// function must be called to unsubscribe.
+func Subscribe() (<-chan struct{}, func()) {
+	c := make(chan struct{}, 1)
+
+	subscribersMu.Lock()
+	subscribers[c] = struct{}{}
+	subscribersMu.Unlock()
+
+	unsubscribe := func() {
+		subscribersMu.Lock()
+		delete(subscribers, c)
+		subscribersMu.Unlock()
+	}
+
+	return c, unsubscribe
+}
+
+func notifySubscribers() {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	for c := range subscribers {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package activity
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	logindService  = "org.freedesktop.login1"
+	logindPath     = dbus.ObjectPath("/org/freedesktop/login1")
+	logindManager  = "org.freedesktop.login1.Manager"
+	logindSession  = "org.freedesktop.login1.Session"
+	dbusProperties = "org.freedesktop.DBus.Properties"
+)
+
+// sessionEvent is a change of the login session that affects tracking. Active
+// is true when the system is about to sleep or the session is locked and
+// false once it wakes up or is unlocked again.
+type sessionEvent struct {
+	Reason string
+	Active bool
+}
+
+// watchLogind subscribes to the signals that logind sends before the system
+// goes to sleep and after it wakes up, and to the ones for locking and
+// unlocking the current session. Screen lockers don't always send Unlock when
+// the user unlocks the screen, so changes of the session's LockedHint are
+// reported too. The returned channel is closed once ctx is done or conn is
+// closed.
+func watchLogind(ctx context.Context, conn *dbus.Conn) (<-chan sessionEvent, error) {
+	err := conn.AddMatchSignalContext(
+		ctx,
+		dbus.WithMatchSender(logindService),
+		dbus.WithMatchObjectPath(logindPath),
+		dbus.WithMatchInterface(logindManager),
+		dbus.WithMatchMember("PrepareForSleep"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("subscribing to PrepareForSleep: %v", err)
+	}
+
+	sessionPath, err := logindSessionPath(ctx, conn)
+	if err != nil {
+		// Sleep is still worth watching without the session.
+		slog.Warn("not watching for screen locks", "err", err)
+	} else {
+		for _, match := range [][]dbus.MatchOption{
+			{dbus.WithMatchInterface(logindSession), dbus.WithMatchMember("Lock")},
+			{dbus.WithMatchInterface(logindSession), dbus.WithMatchMember("Unlock")},
+			{
+				dbus.WithMatchInterface(dbusProperties),
+				dbus.WithMatchMember("PropertiesChanged"),
+				dbus.WithMatchArg(0, logindSession),
+			},
+		} {
+			match = append(match, dbus.WithMatchSender(logindService), dbus.WithMatchObjectPath(sessionPath))
+			if err := conn.AddMatchSignalContext(ctx, match...); err != nil {
+				return nil, fmt.Errorf("subscribing to the session's signals: %v", err)
+			}
+		}
+	}
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	events := make(chan sessionEvent)
+	go func() {
+		defer close(events)
+		defer conn.RemoveSignal(signals)
+
+		for {
+			select {
+			case signal, ok := <-signals:
+				if !ok {
+					return
+				}
+
+				event, ok := parseLogindSignal(signal, sessionPath)
+				if !ok {
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// logindSessionPath looks up the object path of the session the tracker runs
+// in. If the tracker isn't part of a session, e.g. because it's started by
+// the user's service manager, logind picks the user's graphical session.
+func logindSessionPath(ctx context.Context, conn *dbus.Conn) (dbus.ObjectPath, error) {
+	sessionID := os.Getenv("XDG_SESSION_ID")
+	if sessionID == "" {
+		sessionID = "auto"
+	}
+
+	var path dbus.ObjectPath
+	err := conn.Object(logindService, logindPath).
+		CallWithContext(ctx, logindManager+".GetSession", 0, sessionID).
+		Store(&path)
+	if err != nil {
+		return "", fmt.Errorf("looking up the session %q: %v", sessionID, err)
+	}
+
+	return path, nil
+}
+
+func parseLogindSignal(signal *dbus.Signal, sessionPath dbus.ObjectPath) (sessionEvent, bool) {
+	if signal.Name == logindManager+".PrepareForSleep" {
+		if len(signal.Body) != 1 {
+			return sessionEvent{}, false
+		}
+		start, ok := signal.Body[0].(bool)
+
+		return sessionEvent{Reason: PauseSleep, Active: start}, ok
+	}
+
+	// The session signals of other sessions might arrive too if the session
+	// couldn't be looked up.
+	if sessionPath == "" || signal.Path != sessionPath {
+		return sessionEvent{}, false
+	}
+
+	switch signal.Name {
+	case logindSession + ".Lock":
+		return sessionEvent{Reason: PauseLock, Active: true}, true
+	case logindSession + ".Unlock":
+		return sessionEvent{Reason: PauseLock, Active: false}, true
+	case dbusProperties + ".PropertiesChanged":
+		if len(signal.Body) < 2 {
+			return sessionEvent{}, false
+		}
+		changed, ok := signal.Body[1].(map[string]dbus.Variant)
+		if !ok {
+			return sessionEvent{}, false
+		}
+		lockedHint, ok := changed["LockedHint"]
+		if !ok {
+			return sessionEvent{}, false
+		}
+		locked, ok := lockedHint.Value().(bool)
+
+		return sessionEvent{Reason: PauseLock, Active: locked}, ok
+	}
+
+	return sessionEvent{}, false
+}
+
+// handleSessionEvent pauses or resumes the tracker. It reports whether the
+// tracker was resumed, in which case the caller should read the focused
+// window right away.
+func handleSessionEvent(event sessionEvent, config *conf.Config) bool {
+	if event.Active {
+		pauseTracking(time.Now(), config, event.Reason)
+		return false
+	}
+
+	return resumeTracking(event.Reason)
+}
@@ -0,0 +1,165 @@
+package activity
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const testBusConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// startDBus starts a private bus that stands in for the system bus and returns
+// its address. The test is skipped if dbus-daemon isn't installed.
+func startDBus(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "bus.conf")
+	config := fmt.Sprintf(testBusConfig, filepath.Join(dir, "bus"))
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("dbus-daemon", "--config-file="+configPath, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read the bus address: %v", err)
+	}
+
+	return strings.TrimSpace(address)
+}
+
+func connectDBus(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// fakeLogind implements the part of logind's manager interface that the
+// tracker calls.
+type fakeLogind struct {
+	sessionPath dbus.ObjectPath
+}
+
+func (l *fakeLogind) GetSession(sessionID string) (dbus.ObjectPath, *dbus.Error) {
+	if sessionID != "auto" {
+		return "", dbus.MakeFailedError(fmt.Errorf("no session %q", sessionID))
+	}
+
+	return l.sessionPath, nil
+}
+
+func TestWatchLogind(t *testing.T) {
+	t.Setenv("XDG_SESSION_ID", "")
+
+	address := startDBus(t)
+	sessionPath := dbus.ObjectPath("/org/freedesktop/login1/session/_32")
+
+	logind := connectDBus(t, address)
+	if err := logind.Export(&fakeLogind{sessionPath: sessionPath}, logindPath, logindManager); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := logind.RequestName(logindService, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("failed to own %s: reply=%v, err=%v", logindService, reply, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := watchLogind(ctx, connectDBus(t, address))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only logind is allowed to pause the tracker.
+	impostor := connectDBus(t, address)
+	if err := impostor.Emit(sessionPath, logindSession+".Lock"); err != nil {
+		t.Fatal(err)
+	}
+
+	emits := []struct {
+		path dbus.ObjectPath
+		name string
+		args []any
+		want sessionEvent
+	}{
+		{logindPath, logindManager + ".PrepareForSleep", []any{true}, sessionEvent{PauseSleep, true}},
+		{logindPath, logindManager + ".PrepareForSleep", []any{false}, sessionEvent{PauseSleep, false}},
+		{sessionPath, logindSession + ".Lock", nil, sessionEvent{PauseLock, true}},
+		{
+			sessionPath,
+			dbusProperties + ".PropertiesChanged",
+			[]any{logindSession, map[string]dbus.Variant{"LockedHint": dbus.MakeVariant(false)}, []string{}},
+			sessionEvent{PauseLock, false},
+		},
+		{sessionPath, logindSession + ".Unlock", nil, sessionEvent{PauseLock, false}},
+	}
+
+	for _, e := range emits {
+		if err := logind.Emit(e.path, e.name, e.args...); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case got := <-events:
+			if got != e.want {
+				t.Errorf("%s: got %+v, want %+v", e.name, got, e.want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: timed out waiting for the event", e.name)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("got an event after the context was canceled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the channel wasn't closed after the context was canceled")
+	}
+}
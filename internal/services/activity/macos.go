@@ -1,11 +1,9 @@
 package activity
 
 import (
+	"context"
 	"database/sql"
 	"time"
-	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/bnuredini/telltime/internal/conf"
 )
@@ -15,7 +13,7 @@ type MacOSWindowCheckResult struct {
 	WindowName string
 }
 
-func initMacOS(db *sql.DB, config *conf.Config) {
+func runMacOS(ctx context.Context, db *sql.DB, config *conf.Config) error {
 	secondsPerWindowCheck := time.Duration(config.WindowCheckInterval) * time.Second
 	windowCheckTicker := time.NewTicker(secondsPerWindowCheck)
 	defer windowCheckTicker.Stop()
@@ -24,26 +22,27 @@ func initMacOS(db *sql.DB, config *conf.Config) {
 	saveTicker := time.NewTicker(secondsPerSave)
 	defer saveTicker.Stop()
 
-	signalC := make(chan os.Signal, 1)
-	signal.Notify(signalC, syscall.SIGINT, syscall.SIGTERM)
+	setBackend(BackendMacOS)
 
-loop:
 	for {
 		select {
 		case <-windowCheckTicker.C:
 			result := checkMacOSWindows(config)
+			recordWindowRead()
 			// INCOMPLETE: We're not saving window IDs for macOS. Handle this
 			// more gracefully.
-			updateCurrentActivity("", result.WindowClass, result.WindowName)
+			updateCurrentActivity(config, WindowInfo{
+				WindowClass: result.WindowClass,
+				WindowName:  result.WindowName,
+			})
+		case newConfig := <-configUpdates:
+			applyConfig(config, newConfig, windowCheckTicker, saveTicker)
 		case <-saveTicker.C:
 			Save(db)
-		case <-signalC:
-			handleGracefulShutdown(db)
-			break loop
+		case <-ctx.Done():
+			return handleGracefulShutdown(db, config)
 		}
 	}
-
-	os.Exit(0)
 }
 
 // INCOMPLETE: Add the osascript.
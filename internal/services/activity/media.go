@@ -0,0 +1,104 @@
+package activity
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	mprisNamePrefix = "org.mpris.MediaPlayer2."
+	mprisPath       = dbus.ObjectPath("/org/mpris/MediaPlayer2")
+	mprisPlayer     = "org.mpris.MediaPlayer2.Player"
+)
+
+// mediaCheckTimeout bounds a media check, which asks every player on the
+// session bus, so that a player that hangs doesn't hold up the tracker.
+const mediaCheckTimeout = time.Second
+
+// mediaPlaying is guarded by mu.
+var mediaPlaying bool
+
+// MediaPlaying reports whether a media player was playing at the last check.
+// Idle detection should treat the user as active while it's true, since
+// nobody touches the keyboard or the mouse while watching a video.
+func MediaPlaying() bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return mediaPlaying
+}
+
+// connectSessionBus connects to the session bus without starting one if
+// there's none, which is the case when the tracker runs outside a desktop
+// session.
+func connectSessionBus() (*dbus.Conn, error) {
+	conn, err := dbus.SessionBusPrivateNoAutoStartup()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = conn.Auth(nil); err == nil {
+		err = conn.Hello()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// checkMediaPlayback asks the media players that implement MPRIS whether
+// they're playing and records the result.
+func checkMediaPlayback(ctx context.Context, conn *dbus.Conn) error {
+	ctx, cancel := context.WithTimeout(ctx, mediaCheckTimeout)
+	defer cancel()
+
+	playing, err := anyMediaPlaying(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if playing != mediaPlaying {
+		slog.Debug("media playback changed", "playing", playing)
+	}
+	mediaPlaying = playing
+
+	return nil
+}
+
+func anyMediaPlaying(ctx context.Context, conn *dbus.Conn) (bool, error) {
+	var names []string
+	err := conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.ListNames", 0).Store(&names)
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range names {
+		if !strings.HasPrefix(name, mprisNamePrefix) {
+			continue
+		}
+
+		var status dbus.Variant
+		err := conn.Object(name, mprisPath).
+			CallWithContext(ctx, dbusProperties+".Get", 0, mprisPlayer, "PlaybackStatus").
+			Store(&status)
+		if err != nil {
+			slog.Debug("failed to read the playback status", "player", name, "err", err)
+			continue
+		}
+
+		if s, _ := status.Value().(string); s == "Playing" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,78 @@
+package activity
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakePlayer implements the part of the MPRIS interface that the tracker reads.
+// Its methods are called from the goroutine of its D-Bus connection.
+type fakePlayer struct {
+	mu     sync.Mutex
+	status string
+}
+
+func (p *fakePlayer) setStatus(status string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.status = status
+}
+
+func (p *fakePlayer) Get(iface string, property string) (dbus.Variant, *dbus.Error) {
+	if iface != mprisPlayer || property != "PlaybackStatus" {
+		return dbus.Variant{}, dbus.MakeFailedError(dbus.ErrMsgUnknownInterface)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return dbus.MakeVariant(p.status), nil
+}
+
+func TestCheckMediaPlayback(t *testing.T) {
+	mediaPlaying = false
+	t.Cleanup(func() { mediaPlaying = false })
+
+	address := startDBus(t)
+	conn := connectDBus(t, address)
+	ctx := context.Background()
+
+	check := func() bool {
+		t.Helper()
+
+		if err := checkMediaPlayback(ctx, conn); err != nil {
+			t.Fatal(err)
+		}
+
+		return MediaPlaying()
+	}
+
+	if check() {
+		t.Error("got MediaPlaying()=true without any players")
+	}
+
+	paused := &fakePlayer{status: "Paused"}
+	playing := &fakePlayer{status: "Paused"}
+	for name, player := range map[string]*fakePlayer{"vlc": paused, "firefox.instance_1_42": playing} {
+		playerConn := connectDBus(t, address)
+		if err := playerConn.Export(player, mprisPath, dbusProperties); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := playerConn.RequestName(mprisNamePrefix+name, dbus.NameFlagDoNotQueue); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if check() {
+		t.Error("got MediaPlaying()=true while every player is paused")
+	}
+
+	playing.setStatus("Playing")
+	if !check() {
+		t.Error("got MediaPlaying()=false while a player is playing")
+	}
+}
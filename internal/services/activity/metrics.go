@@ -0,0 +1,142 @@
+package activity
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	BackendX11Events   = "x11-events"
+	BackendX11Polling  = "x11-polling"
+	BackendMacOS       = "macos-polling"
+	BackendWindowsHook = "windows-hook"
+)
+
+// TrackerStats describe how the tracker itself is doing, as opposed to the
+// activity it records.
+type TrackerStats struct {
+	Backend        string
+	StartedAt      time.Time
+	Saves          int64
+	FailedSaves    int64
+	SaveSecondsSum float64
+	LastSaveSecs   float64
+	LastSave       time.Time
+	LastSaveError  string
+	TickErrors     int64
+	LastTickError  string
+	LastWindowRead time.Time
+	PendingEvents  int
+}
+
+// statsMu guards trackerStats. It's separate from mu so that the window
+// checks, which run outside of mu, can record errors without waiting for a
+// save to finish.
+var statsMu sync.Mutex
+var trackerStats TrackerStats
+
+func GetTrackerStats() TrackerStats {
+	mu.Lock()
+	pendingEvents := len(windowChanges)
+	mu.Unlock()
+
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	stats := trackerStats
+	stats.PendingEvents = pendingEvents
+
+	return stats
+}
+
+// Problems lists the reasons why the tracker might not be recording activity
+// correctly. The tracker is healthy if the list is empty.
+func (s TrackerStats) Problems(now time.Time, windowCheckInterval time.Duration, saveInterval time.Duration) []string {
+	var problems []string
+
+	if s.StartedAt.IsZero() {
+		return append(problems, "the tracker hasn't started")
+	}
+
+	// Allow a few missed checks so that a single slow one doesn't count.
+	maxWindowReadAge := 3 * windowCheckInterval
+	if maxWindowReadAge < time.Minute {
+		maxWindowReadAge = time.Minute
+	}
+
+	lastWindowRead := s.LastWindowRead
+	if lastWindowRead.IsZero() {
+		lastWindowRead = s.StartedAt
+	}
+	// The Windows hook only reads the focused window when it changes, so
+	// not reading it for a while is expected there.
+	if s.Backend != BackendWindowsHook && now.Sub(lastWindowRead) > maxWindowReadAge {
+		problem := fmt.Sprintf("the focused window hasn't been read for %v", now.Sub(lastWindowRead).Round(time.Second))
+		if s.LastTickError != "" {
+			problem += fmt.Sprintf(" (last error: %s)", s.LastTickError)
+		}
+		problems = append(problems, problem)
+	}
+
+	if s.LastSaveError != "" {
+		problems = append(problems, fmt.Sprintf("the last save failed: %s", s.LastSaveError))
+	}
+
+	lastSave := s.LastSave
+	if lastSave.IsZero() {
+		lastSave = s.StartedAt
+	}
+	if s.PendingEvents > 0 && now.Sub(lastSave) > 2*saveInterval {
+		problems = append(problems, fmt.Sprintf("%d events haven't been saved for %v", s.PendingEvents, now.Sub(lastSave).Round(time.Second)))
+	}
+
+	return problems
+}
+
+// setBackend records which mechanism is used to find the focused window. The
+// first call also marks the start of the tracker.
+func setBackend(backend string) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	if trackerStats.StartedAt.IsZero() {
+		trackerStats.StartedAt = time.Now()
+	}
+	trackerStats.Backend = backend
+}
+
+func recordSave(duration time.Duration, err error) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	if err != nil {
+		trackerStats.FailedSaves++
+		trackerStats.LastSaveError = err.Error()
+		return
+	}
+
+	trackerStats.Saves++
+	trackerStats.SaveSecondsSum += duration.Seconds()
+	trackerStats.LastSaveSecs = duration.Seconds()
+	trackerStats.LastSave = time.Now()
+	trackerStats.LastSaveError = ""
+}
+
+// recordWindowRead marks a successful read of the focused window.
+func recordWindowRead() {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	trackerStats.LastWindowRead = time.Now()
+}
+
+// recordTickError counts an error that occurred while reading the focused
+// window during a window check.
+func recordTickError(err error) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	trackerStats.TickErrors++
+	trackerStats.LastTickError = err.Error()
+}
@@ -0,0 +1,57 @@
+package activity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackerStatsProblems(t *testing.T) {
+	now := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) time.Time {
+		return now.Add(-d)
+	}
+
+	tests := []struct {
+		name  string
+		stats TrackerStats
+		want  int
+	}{
+		{
+			name:  "not started",
+			stats: TrackerStats{},
+			want:  1,
+		},
+		{
+			name:  "just started",
+			stats: TrackerStats{StartedAt: ago(10 * time.Second)},
+			want:  0,
+		},
+		{
+			name:  "healthy",
+			stats: TrackerStats{StartedAt: ago(time.Hour), LastWindowRead: ago(5 * time.Second), LastSave: ago(time.Minute), PendingEvents: 3},
+			want:  0,
+		},
+		{
+			name:  "window reads stopped",
+			stats: TrackerStats{StartedAt: ago(time.Hour), LastWindowRead: ago(10 * time.Minute), LastTickError: "BadWindow"},
+			want:  1,
+		},
+		{
+			name:  "no window changes on Windows",
+			stats: TrackerStats{Backend: BackendWindowsHook, StartedAt: ago(time.Hour), LastWindowRead: ago(10 * time.Minute)},
+			want:  0,
+		},
+		{
+			name:  "saves are failing",
+			stats: TrackerStats{StartedAt: ago(time.Hour), LastWindowRead: ago(5 * time.Second), LastSave: ago(40 * time.Minute), LastSaveError: "database is locked", PendingEvents: 12},
+			want:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		got := tt.stats.Problems(now, 5*time.Second, 5*time.Minute)
+		if len(got) != tt.want {
+			t.Errorf("%s: got %d problems (%q), want %d", tt.name, len(got), got, tt.want)
+		}
+	}
+}
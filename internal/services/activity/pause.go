@@ -0,0 +1,119 @@
+package activity
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+)
+
+// Reasons for pausing the tracker.
+const (
+	PauseSleep = "sleep"
+	PauseLock  = "lock"
+)
+
+// clockJumpThreshold is how far the wall clock can move away from the
+// monotonic clock between two window checks before it's treated as a jump.
+// The monotonic clock doesn't advance while the system is suspended, so this
+// also catches suspends that the tracker wasn't told about.
+const clockJumpThreshold = 30 * time.Second
+
+// pausedFor holds the reasons why the tracker is paused and is guarded by
+// mu. Windows aren't recorded while it's not empty, so that the time spent
+// asleep or behind the lock screen isn't added to the window that was
+// focused before.
+var pausedFor = make(map[string]bool)
+
+// Paused reports whether the tracker is paused, e.g. because the screen is
+// locked.
+func Paused() bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return len(pausedFor) > 0
+}
+
+// pauseTracking closes the event of the current window at now and stops
+// recording windows until resumeTracking is called with the same reason. The
+// closed event is journaled, so it isn't lost if the system never wakes up.
+func pauseTracking(now time.Time, config *conf.Config, reason string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if pausedFor[reason] {
+		return
+	}
+	slog.Info("pausing the tracker", "reason", reason)
+	pausedFor[reason] = true
+
+	breakCurrentActivity(now, config)
+}
+
+// resumeTracking removes a reason for pausing the tracker. It reports whether
+// the tracker is running again, in which case the caller should read the
+// focused window right away instead of waiting for it to change.
+func resumeTracking(reason string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !pausedFor[reason] {
+		return false
+	}
+	slog.Info("resuming the tracker", "reason", reason)
+	delete(pausedFor, reason)
+
+	return len(pausedFor) == 0
+}
+
+// clockJumped reports whether the wall clock moved differently than the
+// monotonic clock between two window checks, given the wall clock times of
+// the checks and the monotonic time that passed in between. If it did, it
+// also returns the wall clock time at which the jump is assumed to have
+// happened, which is where the current event should end.
+func clockJumped(prevCheck time.Time, now time.Time, elapsed time.Duration) (time.Time, bool) {
+	if (now.Sub(prevCheck) - elapsed).Abs() <= clockJumpThreshold {
+		return time.Time{}, false
+	}
+
+	return prevCheck.Add(elapsed), true
+}
+
+// handleClockJump closes the event of the current window where the wall clock
+// jumped so that the gap isn't counted. It's a fallback for suspends that
+// weren't announced and for clock changes. The window is recorded again from
+// the next window check.
+func handleClockJump(prevCheck time.Time, now time.Time, config *conf.Config) bool {
+	// Round(0) strips the monotonic clock reading, so that Sub compares the
+	// wall clock times.
+	end, jumped := clockJumped(prevCheck.Round(0), now.Round(0), now.Sub(prevCheck))
+	if !jumped {
+		return false
+	}
+	slog.Warn("the wall clock jumped", "from", end, "to", now.Round(0))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	breakCurrentActivity(end, config)
+
+	return true
+}
+
+// breakCurrentActivity closes the event of the current window at end. The
+// caller must hold mu.
+func breakCurrentActivity(end time.Time, config *conf.Config) {
+	if lastWindow == nil {
+		return
+	}
+
+	// The window was focused after end, e.g. right after waking up.
+	if end.Before(lastWindow.StartTimestamp) {
+		return
+	}
+
+	settleTitle(end, config)
+	addWindowChange(closeWindow(lastWindow, end))
+	lastWindow = nil
+	notifySubscribers()
+}
@@ -0,0 +1,100 @@
+package activity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+)
+
+func TestPauseTracking(t *testing.T) {
+	windowChanges = nil
+	lastWindow = nil
+	pausedFor = make(map[string]bool)
+	t.Cleanup(func() {
+		windowChanges = nil
+		lastWindow = nil
+		pausedFor = make(map[string]bool)
+	})
+
+	config := &conf.Config{}
+	start := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+
+	recordWindow(start, config, WindowInfo{WindowID: "1", WindowClass: "firefox"})
+	pauseTracking(start.Add(10*time.Minute), config, PauseLock)
+
+	if len(windowChanges) != 1 || windowChanges[0].DurationSecs != 600 {
+		t.Fatalf("got windowChanges=%+v, want one event lasting 10m", windowChanges)
+	}
+
+	updateCurrentActivity(config, WindowInfo{WindowID: "1", WindowClass: "firefox"})
+	if _, ok := CurrentWindow(); ok {
+		t.Fatal("a window was recorded while the tracker was paused")
+	}
+
+	// Locking the screen and then suspending needs both to be undone.
+	pauseTracking(start.Add(11*time.Minute), config, PauseSleep)
+	if resumeTracking(PauseLock) {
+		t.Error("the tracker resumed while the system was still asleep")
+	}
+	if !Paused() {
+		t.Error("got Paused()=false, want true")
+	}
+	if !resumeTracking(PauseSleep) {
+		t.Error("the tracker didn't resume after waking up")
+	}
+
+	updateCurrentActivity(config, WindowInfo{WindowID: "1", WindowClass: "firefox"})
+	if _, ok := CurrentWindow(); !ok {
+		t.Error("no window was recorded after resuming")
+	}
+	if len(windowChanges) != 1 {
+		t.Errorf("got %d events, want 1", len(windowChanges))
+	}
+}
+
+func TestClockJumped(t *testing.T) {
+	prevCheck := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		elapsed time.Duration
+		want    bool
+		wantEnd time.Time
+	}{
+		{
+			name:    "regular tick",
+			now:     prevCheck.Add(5 * time.Second),
+			elapsed: 5 * time.Second,
+		},
+		{
+			name:    "late tick",
+			now:     prevCheck.Add(20 * time.Second),
+			elapsed: 20 * time.Second,
+		},
+		{
+			name:    "suspend",
+			now:     prevCheck.Add(8 * time.Hour),
+			elapsed: 3 * time.Second,
+			want:    true,
+			wantEnd: prevCheck.Add(3 * time.Second),
+		},
+		{
+			name:    "clock set back",
+			now:     prevCheck.Add(-time.Hour),
+			elapsed: 5 * time.Second,
+			want:    true,
+			wantEnd: prevCheck.Add(5 * time.Second),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, got := clockJumped(prevCheck, tt.now, tt.elapsed)
+			if got != tt.want || !end.Equal(tt.wantEnd) {
+				t.Errorf("got (%v, %v), want (%v, %v)", end, got, tt.wantEnd, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+)
+
+// LongCommandDuration is how long a command has to run to be listed among the
+// long-running commands.
+const LongCommandDuration = time.Minute
+
+// maxLongCommands limits how many long-running commands are reported.
+const maxLongCommands = 10
+
+// ShellEvent is what the shell hooks send after a command finishes.
+type ShellEvent struct {
+	Start      time.Time
+	End        time.Time
+	Command    string
+	Cwd        string
+	ExitStatus *int
+	Shell      string
+}
+
+type ShellCommand struct {
+	Command      string
+	Dir          string
+	Start        time.Time
+	DurationSecs int64
+	ExitStatus   sql.NullInt64
+}
+
+type ShellStats struct {
+	TotalSecs    int64
+	ByDirectory  []*CodingStat
+	LongCommands []*ShellCommand
+}
+
+// SaveShellEvent stores a command that was run in a shell together with the
+// class of the window that was focused when it started, which is usually the
+// terminal emulator it was run in. Unless the user opted in, only the name of
+// the program is stored since command lines often contain secrets.
+func SaveShellEvent(ctx context.Context, q *dbgen.Queries, config *conf.Config, e ShellEvent) error {
+	if strings.TrimSpace(e.Command) == "" {
+		return fmt.Errorf("a shell event needs a command")
+	}
+	if e.End.IsZero() {
+		e.End = time.Now()
+	}
+	if e.Start.IsZero() || e.Start.After(e.End) {
+		e.Start = e.End
+	}
+
+	if !config.RecordCommandLines {
+		e.Command = strings.Fields(e.Command)[0]
+	}
+
+	windowClass, err := windowClassAt(ctx, q, e.Start)
+	if err != nil {
+		return fmt.Errorf("finding the focused window: %v", err)
+	}
+
+	var exitStatus sql.NullInt64
+	if e.ExitStatus != nil {
+		exitStatus = sql.NullInt64{Int64: int64(*e.ExitStatus), Valid: true}
+	}
+
+	err = q.InsertShellEvent(ctx, dbgen.InsertShellEventParams{
+		StartTime:   e.Start.Unix(),
+		Duration:    int64(e.End.Sub(e.Start).Seconds()),
+		Command:     e.Command,
+		Cwd:         toNullString(e.Cwd),
+		Repo:        toNullString(repoRoot(e.Cwd)),
+		ExitStatus:  exitStatus,
+		Shell:       toNullString(e.Shell),
+		WindowClass: toNullString(windowClass),
+	})
+	if err != nil {
+		return fmt.Errorf("saving the shell event: %v", err)
+	}
+
+	return nil
+}
+
+// windowClassAt returns the class of the window that was focused at t or an
+// empty string if nothing was recorded at that time.
+func windowClassAt(ctx context.Context, q *dbgen.Queries, t time.Time) (string, error) {
+	for _, s := range pendingSegments(time.Now()) {
+		if !s.Start.After(t) && s.End.After(t) {
+			return s.Label, nil
+		}
+	}
+
+	events, err := q.GetEventsInRange(ctx, dbgen.GetEventsInRangeParams{
+		StartTime: t.Unix(),
+		EndTime:   t.Unix() + 1,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(events) == 0 {
+		return "", nil
+	}
+
+	return events[len(events)-1].WindowClass, nil
+}
+
+// repoRoot returns the closest directory containing dir that is the root of a
+// Git repository or an empty string if dir isn't inside one.
+func repoRoot(dir string) string {
+	if dir == "" || !filepath.IsAbs(dir) {
+		return ""
+	}
+
+	for dir = filepath.Clean(dir); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		if dir == filepath.Dir(dir) {
+			return ""
+		}
+	}
+}
+
+func GetShellStats(ctx context.Context, q *dbgen.Queries, start time.Time, end time.Time) (*ShellStats, error) {
+	events, err := q.GetShellEventsInRange(ctx, dbgen.GetShellEventsInRangeParams{
+		StartTime: start.Unix(),
+		EndTime:   end.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ShellStatsFromEvents(events, start, end), nil
+}
+
+// ShellStatsFromEvents adds up the time spent running commands between start
+// and end by the repository they were run in, or by the working directory if
+// it isn't part of a repository.
+func ShellStatsFromEvents(events []dbgen.ShellEvent, start time.Time, end time.Time) *ShellStats {
+	byDirectory := make(map[string]*CodingStat)
+	stats := &ShellStats{}
+
+	for _, e := range events {
+		eventStart := time.Unix(e.StartTime, 0)
+		eventEnd := eventStart.Add(time.Duration(e.Duration) * time.Second)
+
+		dir := e.Repo.String
+		if dir == "" {
+			dir = e.Cwd.String
+		}
+
+		if e.Duration >= int64(LongCommandDuration.Seconds()) {
+			stats.LongCommands = append(stats.LongCommands, &ShellCommand{
+				Command:      e.Command,
+				Dir:          dir,
+				Start:        eventStart,
+				DurationSecs: e.Duration,
+				ExitStatus:   e.ExitStatus,
+			})
+		}
+
+		secs := int64(minTime(eventEnd, end).Sub(maxTime(eventStart, start)).Seconds())
+		if secs <= 0 {
+			continue
+		}
+
+		if dir == "" {
+			dir = "Unknown"
+		}
+		stat, ok := byDirectory[dir]
+		if !ok {
+			stat = &CodingStat{Name: dir}
+			byDirectory[dir] = stat
+		}
+		stat.DurationSecs += secs
+		stats.TotalSecs += secs
+	}
+
+	stats.ByDirectory = sortedCodingStats(byDirectory)
+
+	sort.SliceStable(stats.LongCommands, func(i, j int) bool {
+		return stats.LongCommands[i].DurationSecs > stats.LongCommands[j].DurationSecs
+	})
+	if len(stats.LongCommands) > maxLongCommands {
+		stats.LongCommands = stats.LongCommands[:maxLongCommands]
+	}
+
+	return stats
+}
@@ -0,0 +1,89 @@
+package activity
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+)
+
+func TestShellStatsFromEvents(t *testing.T) {
+	base := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	at := func(mins int) time.Time {
+		return base.Add(time.Duration(mins) * time.Minute)
+	}
+	event := func(startMins int, durationMins int, command string, cwd string, repo string) dbgen.ShellEvent {
+		return dbgen.ShellEvent{
+			StartTime: at(startMins).Unix(),
+			Duration:  int64(durationMins * 60),
+			Command:   command,
+			Cwd:       sql.NullString{String: cwd, Valid: cwd != ""},
+			Repo:      sql.NullString{String: repo, Valid: repo != ""},
+		}
+	}
+
+	events := []dbgen.ShellEvent{
+		// Started before the range, so only 5 minutes count.
+		event(-5, 10, "make", "/src/telltime/cmd", "/src/telltime"),
+		event(10, 0, "ls", "/tmp", ""),
+		event(20, 3, "go", "/src/telltime", "/src/telltime"),
+		event(30, 2, "rsync", "/home/user", ""),
+	}
+
+	got := ShellStatsFromEvents(events, at(0), at(60))
+
+	if got.TotalSecs != 10*60 {
+		t.Errorf("got total=%d, want=%d", got.TotalSecs, 10*60)
+	}
+
+	wantDirs := []CodingStat{{"/src/telltime", 8 * 60}, {"/home/user", 2 * 60}}
+	if len(got.ByDirectory) != len(wantDirs) {
+		t.Fatalf("got %d directories, want %d", len(got.ByDirectory), len(wantDirs))
+	}
+	for i, w := range wantDirs {
+		if *got.ByDirectory[i] != w {
+			t.Errorf("directory %d: got=%+v, want=%+v", i, *got.ByDirectory[i], w)
+		}
+	}
+
+	wantCommands := []string{"make", "go", "rsync"}
+	if len(got.LongCommands) != len(wantCommands) {
+		t.Fatalf("got %d long commands, want %d", len(got.LongCommands), len(wantCommands))
+	}
+	for i, w := range wantCommands {
+		if got.LongCommands[i].Command != w {
+			t.Errorf("long command %d: got=%q, want=%q", i, got.LongCommands[i].Command, w)
+		}
+	}
+}
+
+func TestRepoRoot(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	nested := filepath.Join(repo, "cmd", "telltime")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{repo, repo},
+		{nested, repo},
+		{root, ""},
+		{"relative/path", ""},
+	}
+
+	for _, tt := range tests {
+		if got := repoRoot(tt.dir); got != tt.want {
+			t.Errorf("repoRoot(%q): got=%q, want=%q", tt.dir, got, tt.want)
+		}
+	}
+}
@@ -4,8 +4,10 @@
 package activity
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"runtime"
 	"syscall"
 	"unsafe"
 
@@ -17,11 +19,13 @@ var (
 	kernel32 = syscall.NewLazyDLL("kernel32.dll")
 	psapi    = syscall.NewLazyDLL("psapi.dll")
 
-	procSetWinEventHook  = user32.NewProc("SetWinEventHook")
-	procUnhookWinEvent   = user32.NewProc("UnhookWinEvent")
-	procGetMessageW      = user32.NewProc("GetMessageW")
-	procTranslateMessage = user32.NewProc("TranslateMessage")
-	procDispatchMessageW = user32.NewProc("DispatchMessageW")
+	procSetWinEventHook    = user32.NewProc("SetWinEventHook")
+	procUnhookWinEvent     = user32.NewProc("UnhookWinEvent")
+	procGetMessageW        = user32.NewProc("GetMessageW")
+	procTranslateMessage   = user32.NewProc("TranslateMessage")
+	procDispatchMessageW   = user32.NewProc("DispatchMessageW")
+	procPostThreadMessageW = user32.NewProc("PostThreadMessageW")
+	procGetCurrentThreadId = kernel32.NewProc("GetCurrentThreadId")
 
 	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
 	procOpenProcess              = kernel32.NewProc("OpenProcess")
@@ -35,6 +39,9 @@ const (
 
 	PROCESS_QUERY_INFORMATION = 0x0400
 	PROCESS_VM_READ           = 0x0010
+
+	WM_QUIT = 0x0012
+	WM_APP  = 0x8000
 )
 
 type MSG struct {
@@ -49,7 +56,12 @@ type MSG struct {
 	}
 }
 
-func initWindows(db *sql.DB, config *conf.Config) {
+func runWindows(ctx context.Context, db *sql.DB, config *conf.Config) error {
+	// The hook delivers its events to the thread that installed it, so the
+	// message loop has to stay on that thread.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
 	hookCallback := syscall.NewCallback(winEventProc)
 
 	hHook, _, err := procSetWinEventHook.Call(
@@ -63,24 +75,65 @@ func initWindows(db *sql.DB, config *conf.Config) {
 	)
 
 	if hHook == 0 {
-		fmt.Println("Failed to set hook:", err)
-		return
+		return fmt.Errorf("setting the window event hook: %v", err)
 	}
+	defer procUnhookWinEvent.Call(hHook)
+
+	// GetMessageW blocks, so the loop is stopped by posting WM_QUIT to it and
+	// woken up with WM_APP once there's a new configuration in
+	// pendingConfigs.
+	threadID, _, _ := procGetCurrentThreadId.Call()
+	pendingConfigs := make(chan conf.Config, 1)
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				procPostThreadMessageW.Call(threadID, WM_QUIT, 0, 0)
+				return
+			case newConfig := <-configUpdates:
+				// Only this goroutine sends, so the send can't block once
+				// a configuration that wasn't applied yet is dropped.
+				select {
+				case <-pendingConfigs:
+				default:
+				}
+				pendingConfigs <- newConfig
+				procPostThreadMessageW.Call(threadID, WM_APP, 0, 0)
+			case <-stopped:
+				return
+			}
+		}
+	}()
 
+	setBackend(BackendWindowsHook)
 	fmt.Println("Listening for active window changes...")
 
 	var msg MSG
 	for {
 		ret, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
 		if int32(ret) == -1 {
+			return fmt.Errorf("the message loop failed")
+		}
+		if ret == 0 {
+			// WM_QUIT was received.
 			break
 		}
+		if msg.Message == WM_APP {
+			select {
+			case newConfig := <-pendingConfigs:
+				applyConfig(config, newConfig, nil, nil)
+			default:
+			}
+			continue
+		}
 
 		procTranslateMessage.Call(uintptr(unsafe.Pointer(&msg)))
 		procDispatchMessageW.Call(uintptr(unsafe.Pointer(&msg)))
 	}
 
-	procUnhookWinEvent.Call(hHook)
+	return handleGracefulShutdown(db, config)
 }
 
 func winEventProc(
@@ -125,6 +178,7 @@ func winEventProc(
 	}
 
 	processName := syscall.UTF16ToString(exeName)
+	recordWindowRead()
 
 	fmt.Printf("Active application changed: %s\n", processName)
 
@@ -4,11 +4,13 @@
 package activity
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/bnuredini/telltime/internal/conf"
 )
 
-func initWindows(db *sql.DB, config *conf.Config) {
-	// TODO: Report if this is called.
+func runWindows(ctx context.Context, db *sql.DB, config *conf.Config) error {
+	return errors.New("the Windows tracker is only available in Windows builds")
 }
@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+)
+
+const (
+	SessionCookie   = "telltime_session"
+	SessionDuration = 30 * 24 * time.Hour
+
+	MinPasswordLength = 8
+)
+
+// The parameters for new password hashes. The iterations follow the OWASP
+// recommendation for PBKDF2 with SHA-256 and are stored with the hash, so
+// they can be raised without invalidating existing passwords.
+const (
+	hashScheme     = "pbkdf2-sha256"
+	hashIterations = 600_000
+	hashSaltBytes  = 16
+	hashKeyBytes   = 32
+)
+
+var (
+	ErrWrongPassword = errors.New("wrong password")
+	ErrNoPassword    = errors.New("no password is set")
+)
+
+// Enabled reports whether a password is set. Once it is, the dashboard
+// requires logging in with it and API clients have to send the API token.
+// Without a password, nothing is protected, which is only allowed while the
+// server listens on the loopback address.
+func Enabled(ctx context.Context, q *dbgen.Queries) (bool, error) {
+	_, err := q.GetPasswordHash(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SetPassword sets or changes the password. Everyone who's logged in is
+// logged out.
+func SetPassword(ctx context.Context, db *sql.DB, password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("the password must have at least %d characters", MinPasswordLength)
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := dbgen.New(tx)
+	err = q.UpsertPassword(ctx, dbgen.UpsertPasswordParams{Hash: hash, UpdatedAt: time.Now().Unix()})
+	if err != nil {
+		return fmt.Errorf("saving the password: %v", err)
+	}
+	if err = q.DeleteSessions(ctx); err != nil {
+		return fmt.Errorf("deleting the sessions: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// RemovePassword disables authentication.
+func RemovePassword(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := dbgen.New(tx)
+	if err = q.DeletePassword(ctx); err != nil {
+		return err
+	}
+	if err = q.DeleteSessions(ctx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Login checks the password and starts a session. It returns the token to
+// store in the session cookie.
+func Login(ctx context.Context, q *dbgen.Queries, password string, now time.Time) (string, error) {
+	hash, err := q.GetPasswordHash(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNoPassword
+	} else if err != nil {
+		return "", err
+	}
+
+	ok, err := checkPassword(hash, password)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrWrongPassword
+	}
+
+	if err = q.DeleteExpiredSessions(ctx, now.Unix()); err != nil {
+		return "", fmt.Errorf("deleting expired sessions: %v", err)
+	}
+
+	token := NewToken()
+	err = q.InsertSession(ctx, dbgen.InsertSessionParams{
+		TokenHash: hashToken(token),
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(SessionDuration).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("saving the session: %v", err)
+	}
+
+	return token, nil
+}
+
+// ValidSession reports whether token belongs to a session that hasn't
+// expired.
+func ValidSession(ctx context.Context, q *dbgen.Queries, token string, now time.Time) (bool, error) {
+	_, err := q.GetSession(ctx, dbgen.GetSessionParams{TokenHash: hashToken(token), Now: now.Unix()})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Logout ends the session of token.
+func Logout(ctx context.Context, q *dbgen.Queries, token string) error {
+	return q.DeleteSession(ctx, hashToken(token))
+}
+
+// ReadAPIToken reads the API token from the file at path. If create is true
+// and the file doesn't exist, a new token is written to it first.
+func ReadAPIToken(path string, create bool) (string, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		return RotateAPIToken(path)
+	} else if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("%q is empty", path)
+	}
+
+	return token, nil
+}
+
+// RotateAPIToken writes a new API token to the file at path. Only the owner
+// can read the file.
+func RotateAPIToken(path string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+
+	token := NewToken()
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	// WriteFile keeps the permissions of an existing file.
+	if err := os.Chmod(path, 0600); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// TokensEqual compares tokens in constant time.
+func TokensEqual(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// NewToken returns a random token that's safe to use in URLs and cookies.
+func NewToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashToken is used for session tokens, which are stored hashed so that the
+// database can't be used to take over a session. The tokens are random, so a
+// fast hash is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashPassword returns the hash of password in the form
+// "scheme$iterations$salt$key".
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, hashSaltBytes)
+	rand.Read(salt)
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, hashIterations, hashKeyBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		hashScheme,
+		strconv.Itoa(hashIterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$"), nil
+}
+
+func checkPassword(encoded string, password string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return false, fmt.Errorf("unknown password hash format")
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false, fmt.Errorf("invalid iterations in the password hash: %v", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, fmt.Errorf("invalid salt in the password hash: %v", err)
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false, fmt.Errorf("invalid key in the password hash: %v", err)
+	}
+
+	got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(got, want) == 1, nil
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		password string
+		want     bool
+	}{
+		{"correct horse", true},
+		{"correct horsE", false},
+		{"", false},
+	} {
+		got, err := checkPassword(hash, tt.password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("password=%q, got=%v, want=%v", tt.password, got, tt.want)
+		}
+	}
+
+	if _, err := checkPassword("md5$abc", "x"); err == nil {
+		t.Error("expected an error for an unknown hash format")
+	}
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.OpenDB(t)
+	q := dbgen.New(db)
+	now := time.Now()
+
+	if _, err := Login(ctx, q, "whatever", now); !errors.Is(err, ErrNoPassword) {
+		t.Fatalf("got %v, want ErrNoPassword", err)
+	}
+	if err := SetPassword(ctx, db, "short"); err == nil {
+		t.Fatal("expected an error for a short password")
+	}
+	if err := SetPassword(ctx, db, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if enabled, err := Enabled(ctx, q); err != nil || !enabled {
+		t.Fatalf("got enabled=%v, err=%v, want enabled", enabled, err)
+	}
+
+	if _, err := Login(ctx, q, "wrong horse", now); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("got %v, want ErrWrongPassword", err)
+	}
+	token, err := Login(ctx, q, "correct horse", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(name string, token string, at time.Time, want bool) {
+		t.Helper()
+		got, err := ValidSession(ctx, q, token, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: got valid=%v, want %v", name, got, want)
+		}
+	}
+	check("fresh", token, now, true)
+	check("unknown", NewToken(), now, false)
+	check("expired", token, now.Add(SessionDuration), false)
+
+	// Changing the password logs everyone out.
+	if err := SetPassword(ctx, db, "battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	check("after changing the password", token, now, false)
+
+	token, err = Login(ctx, q, "battery staple", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Logout(ctx, q, token); err != nil {
+		t.Fatal(err)
+	}
+	check("after logging out", token, now, false)
+}
+
+func TestReadAPIToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-token")
+
+	if _, err := ReadAPIToken(path, false); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got %v, want ErrNotExist", err)
+	}
+
+	created, err := ReadAPIToken(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("got permissions %v, want 0600", perm)
+	}
+
+	read, err := ReadAPIToken(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !TokensEqual(read, created) {
+		t.Errorf("got %q, want the created token %q", read, created)
+	}
+
+	rotated, err := RotateAPIToken(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if TokensEqual(rotated, created) {
+		t.Error("rotating kept the same token")
+	}
+}
@@ -0,0 +1,389 @@
+package devicesync
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/services/auth"
+)
+
+// EventsPath is where the server accepts pushed events.
+const EventsPath = "/api/sync/events"
+
+// MaxBatchEvents is how many events are pushed per request. It keeps the
+// requests well below the body limit of the API even with long titles.
+const MaxBatchEvents = 200
+
+const (
+	maxDeviceIDLength = 64
+	maxHostnameLength = 255
+	pushTimeout       = 30 * time.Second
+)
+
+var (
+	ErrInvalidBatch = errors.New("invalid batch")
+
+	// ErrGap is returned when a batch doesn't continue where the events the
+	// server has for the device end, e.g. because the server's database was
+	// restored from a backup. The client starts over from the last event the
+	// server has.
+	ErrGap = errors.New("the server is missing earlier events")
+)
+
+// Event is a closed window event as it's pushed to the server. Seq is the ID
+// of the event in the database of the device that recorded it, so it only
+// ever increases.
+type Event struct {
+	Seq         int64  `json:"seq"`
+	Start       int64  `json:"start"`
+	Duration    int64  `json:"duration"`
+	WindowClass string `json:"window_class"`
+	WindowTitle string `json:"window_title,omitempty"`
+	ExePath     string `json:"exe_path,omitempty"`
+	Cmdline     string `json:"cmdline,omitempty"`
+}
+
+// Batch is the body of a push. After is the sequence number of the last event
+// the client knows the server has. Batches can be pushed more than once;
+// events the server already has are skipped.
+type Batch struct {
+	DeviceID string  `json:"device_id"`
+	Hostname string  `json:"hostname"`
+	After    int64   `json:"after"`
+	Events   []Event `json:"events"`
+}
+
+// Ack is the response to a push. LastSeq is the sequence number of the last
+// event the server has for the device, which is where the next batch starts.
+type Ack struct {
+	LastSeq int64 `json:"last_seq"`
+}
+
+// State describes how syncing with the server is going.
+type State struct {
+	Server       string
+	LastAttempt  time.Time
+	LastSuccess  time.Time
+	LastError    string
+	PushedEvents int64
+}
+
+var stateMu sync.Mutex
+var state State
+
+func GetState() State {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	return state
+}
+
+// Run pushes new events to config.SyncServer right away and then every
+// SyncInterval until ctx is canceled. The server not being reachable isn't an
+// error since the events are pushed once it is again.
+func Run(ctx context.Context, db *sql.DB, config *conf.Config) error {
+	q := dbgen.New(db)
+	client := &http.Client{Timeout: pushTimeout}
+	serverURL := normalizeURL(config.SyncServer)
+
+	stateMu.Lock()
+	state.Server = serverURL
+	stateMu.Unlock()
+
+	ticker := time.NewTicker(time.Duration(config.SyncInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		pushed, err := pushWithToken(ctx, q, client, serverURL, config.SyncTokenPath)
+		recordPush(time.Now(), pushed, err)
+		if err != nil && ctx.Err() == nil {
+			slog.Warn("failed to sync", "server", serverURL, "err", err)
+		} else if pushed > 0 {
+			slog.Debug("synced events", "server", serverURL, "pushed", pushed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// PushOnce pushes the events that the server doesn't have yet and returns how
+// many were pushed.
+func PushOnce(ctx context.Context, q *dbgen.Queries, config *conf.Config) (int, error) {
+	if config.SyncServer == "" {
+		return 0, fmt.Errorf("no sync server is set (see -sync-server)")
+	}
+
+	client := &http.Client{Timeout: pushTimeout}
+	return pushWithToken(ctx, q, client, normalizeURL(config.SyncServer), config.SyncTokenPath)
+}
+
+func pushWithToken(ctx context.Context, q *dbgen.Queries, client *http.Client, serverURL string, tokenPath string) (int, error) {
+	token, err := auth.ReadAPIToken(tokenPath, false)
+	if errors.Is(err, os.ErrNotExist) {
+		token = ""
+	} else if err != nil {
+		return 0, fmt.Errorf("reading the sync token: %v", err)
+	}
+
+	return Push(ctx, q, client, serverURL, token)
+}
+
+// Push sends the closed events recorded on this device that the server doesn't
+// have yet in batches of MaxBatchEvents. Events received from other devices
+// aren't pushed on, so devices can't send events back and forth. Open events
+// stop the push, since they're still changing; they're pushed once closed.
+//
+// At least one batch is sent, even if it's empty, so that the server notices
+// the device and any gaps.
+func Push(ctx context.Context, q *dbgen.Queries, client *http.Client, serverURL string, token string) (int, error) {
+	device, err := activity.LocalDevice(ctx, q, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("reading the device: %v", err)
+	}
+
+	after, err := q.GetSyncCursor(ctx, serverURL)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("reading the sync cursor: %v", err)
+	}
+
+	pushed := 0
+	startedOver := false
+	for {
+		rows, err := q.GetUnsyncedEvents(ctx, dbgen.GetUnsyncedEventsParams{
+			AfterID: after,
+			Limit:   MaxBatchEvents,
+		})
+		if err != nil {
+			return pushed, err
+		}
+
+		batch := Batch{
+			DeviceID: device.ID,
+			Hostname: device.Hostname,
+			After:    after,
+			Events:   make([]Event, 0, len(rows)),
+		}
+		for _, row := range rows {
+			if row.IsOpen == 1 {
+				break
+			}
+			batch.Events = append(batch.Events, Event{
+				Seq:         row.ID,
+				Start:       row.StartTime,
+				Duration:    row.Duration,
+				WindowClass: row.WindowClass,
+				WindowTitle: row.WindowTitle.String,
+				ExePath:     row.ExePath.String,
+				Cmdline:     row.Cmdline.String,
+			})
+		}
+
+		ack, err := send(ctx, client, serverURL, token, batch)
+		if errors.Is(err, ErrGap) && !startedOver {
+			slog.Info("the sync server is missing events; starting over", "server", serverURL, "lastSeq", ack.LastSeq)
+			startedOver = true
+			after = ack.LastSeq
+			continue
+		} else if err != nil {
+			return pushed, err
+		}
+
+		after = ack.LastSeq
+		err = q.UpsertSyncCursor(ctx, dbgen.UpsertSyncCursorParams{
+			ServerUrl: serverURL,
+			LastSeq:   after,
+			UpdatedAt: time.Now().Unix(),
+		})
+		if err != nil {
+			return pushed, fmt.Errorf("saving the sync cursor: %v", err)
+		}
+
+		pushed += len(batch.Events)
+		if len(batch.Events) < MaxBatchEvents {
+			return pushed, nil
+		}
+	}
+}
+
+func send(ctx context.Context, client *http.Client, serverURL string, token string, batch Batch) (Ack, error) {
+	var ack Ack
+
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return ack, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, serverURL+EventsPath, bytes.NewReader(body))
+	if err != nil {
+		return ack, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ack, err
+	}
+	defer resp.Body.Close()
+
+	var respBody struct {
+		Ack
+		Error string `json:"error"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return ack, fmt.Errorf("the server responded with %q", resp.Status)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return respBody.Ack, nil
+	case http.StatusConflict:
+		return respBody.Ack, ErrGap
+	}
+
+	return ack, fmt.Errorf("the server responded with %q: %s", resp.Status, respBody.Error)
+}
+
+// Receive stores the events of a batch pushed by another device and returns
+// the sequence number of the last event stored for it.
+func Receive(ctx context.Context, db *sql.DB, batch Batch, now time.Time) (int64, error) {
+	if err := validateBatch(batch); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	q := dbgen.New(tx)
+
+	localDevice, err := activity.LocalDevice(ctx, q, now)
+	if err != nil {
+		return 0, err
+	}
+	if batch.DeviceID == localDevice.ID {
+		return 0, fmt.Errorf("%w: %q is the ID of this device", ErrInvalidBatch, batch.DeviceID)
+	}
+
+	device, err := q.GetDevice(ctx, batch.DeviceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		device = dbgen.Device{ID: batch.DeviceID, FirstSeenAt: now.Unix()}
+		err = q.InsertDevice(ctx, dbgen.InsertDeviceParams{
+			ID:          batch.DeviceID,
+			Hostname:    batch.Hostname,
+			IsLocal:     0,
+			FirstSeenAt: now.Unix(),
+			LastSeenAt:  now.Unix(),
+		})
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if batch.After > device.LastSeq {
+		return device.LastSeq, ErrGap
+	}
+
+	lastSeq := device.LastSeq
+	for _, e := range batch.Events {
+		_, err = q.InsertSyncedEvent(ctx, dbgen.InsertSyncedEventParams{
+			StartTime:   e.Start,
+			WindowClass: e.WindowClass,
+			WindowTitle: toNullString(e.WindowTitle),
+			Duration:    e.Duration,
+			ExePath:     toNullString(e.ExePath),
+			Cmdline:     toNullString(e.Cmdline),
+			DeviceID:    sql.NullString{String: batch.DeviceID, Valid: true},
+			Seq:         sql.NullInt64{Int64: e.Seq, Valid: true},
+		})
+		if err != nil {
+			return 0, err
+		}
+		lastSeq = max(lastSeq, e.Seq)
+	}
+
+	err = q.UpdateDevice(ctx, dbgen.UpdateDeviceParams{
+		Hostname:   batch.Hostname,
+		LastSeq:    lastSeq,
+		LastSeenAt: now.Unix(),
+		ID:         batch.DeviceID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return lastSeq, tx.Commit()
+}
+
+func validateBatch(batch Batch) error {
+	if batch.DeviceID == "" || len(batch.DeviceID) > maxDeviceIDLength {
+		return fmt.Errorf("%w: device_id must have between 1 and %d characters", ErrInvalidBatch, maxDeviceIDLength)
+	}
+	if len(batch.Hostname) > maxHostnameLength {
+		return fmt.Errorf("%w: hostname can't have more than %d characters", ErrInvalidBatch, maxHostnameLength)
+	}
+	if len(batch.Events) > MaxBatchEvents {
+		return fmt.Errorf("%w: a batch can't have more than %d events", ErrInvalidBatch, MaxBatchEvents)
+	}
+
+	prevSeq := batch.After
+	for _, e := range batch.Events {
+		if e.Seq <= prevSeq {
+			return fmt.Errorf("%w: the sequence numbers must increase and follow after", ErrInvalidBatch)
+		}
+		if strings.TrimSpace(e.WindowClass) == "" {
+			return fmt.Errorf("%w: event %d has no window_class", ErrInvalidBatch, e.Seq)
+		}
+		if e.Duration < 0 {
+			return fmt.Errorf("%w: event %d has a negative duration", ErrInvalidBatch, e.Seq)
+		}
+		prevSeq = e.Seq
+	}
+
+	return nil
+}
+
+func recordPush(now time.Time, pushed int, err error) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	state.LastAttempt = now
+	state.PushedEvents += int64(pushed)
+	if err != nil {
+		state.LastError = err.Error()
+		return
+	}
+
+	state.LastSuccess = now
+	state.LastError = ""
+}
+
+func normalizeURL(serverURL string) string {
+	return strings.TrimRight(strings.TrimSpace(serverURL), "/")
+}
+
+func toNullString(s string) sql.NullString {
+	s = strings.TrimSpace(s)
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,190 @@
+package devicesync
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+// newTestServer accepts pushes the way the sync endpoint does. The database
+// can be swapped to simulate a server that lost its data.
+func newTestServer(t *testing.T, db **sql.DB) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch Batch
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		lastSeq, err := Receive(r.Context(), *db, batch, time.Now())
+		status := http.StatusOK
+		if errors.Is(err, ErrGap) {
+			status = http.StatusConflict
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(Ack{LastSeq: lastSeq})
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func insertEvent(t *testing.T, q *dbgen.Queries, start int64, class string) {
+	t.Helper()
+
+	err := q.InsertEvents(context.Background(), dbgen.InsertEventsParams{
+		StartTime:   start,
+		WindowClass: class,
+		Duration:    60,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func countDeviceEvents(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM event WHERE device_id IS NOT NULL").Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func TestPushIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	clientQ := dbgen.New(testutil.OpenDB(t))
+	serverDB := testutil.OpenDB(t)
+	srv := newTestServer(t, &serverDB)
+
+	insertEvent(t, clientQ, 1000, "firefox")
+	insertEvent(t, clientQ, 1060, "Alacritty")
+	openID, err := clientQ.InsertOpenEvent(ctx, dbgen.InsertOpenEventParams{StartTime: 1120, WindowClass: "Code"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushed, err := Push(ctx, clientQ, srv.Client(), srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pushed != 2 {
+		t.Errorf("pushed %d events, want 2 (the open event waits until it's closed)", pushed)
+	}
+
+	pushed, err = Push(ctx, clientQ, srv.Client(), srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pushed != 0 {
+		t.Errorf("pushed %d events again, want 0", pushed)
+	}
+
+	if err = clientQ.CloseEvent(ctx, openID); err != nil {
+		t.Fatal(err)
+	}
+	if pushed, err = Push(ctx, clientQ, srv.Client(), srv.URL, ""); err != nil {
+		t.Fatal(err)
+	}
+	if pushed != 1 {
+		t.Errorf("pushed %d events after closing the open one, want 1", pushed)
+	}
+
+	if got := countDeviceEvents(t, serverDB); got != 3 {
+		t.Errorf("the server has %d events, want 3", got)
+	}
+
+	// Resending a batch the server already has doesn't duplicate anything.
+	device, err := activity.LocalDevice(ctx, clientQ, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := Batch{DeviceID: device.ID, Events: []Event{{Seq: 1, Start: 1000, Duration: 60, WindowClass: "firefox"}}}
+	if _, err = Receive(ctx, serverDB, batch, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got := countDeviceEvents(t, serverDB); got != 3 {
+		t.Errorf("the server has %d events after a resend, want 3", got)
+	}
+}
+
+func TestPushStartsOverAfterGap(t *testing.T) {
+	ctx := context.Background()
+	clientQ := dbgen.New(testutil.OpenDB(t))
+	serverDB := testutil.OpenDB(t)
+	srv := newTestServer(t, &serverDB)
+
+	insertEvent(t, clientQ, 1000, "firefox")
+	insertEvent(t, clientQ, 1060, "Alacritty")
+	if _, err := Push(ctx, clientQ, srv.Client(), srv.URL, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server loses its database.
+	serverDB = testutil.OpenDB(t)
+	insertEvent(t, clientQ, 1120, "Code")
+
+	pushed, err := Push(ctx, clientQ, srv.Client(), srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pushed != 3 {
+		t.Errorf("pushed %d events, want 3", pushed)
+	}
+	if got := countDeviceEvents(t, serverDB); got != 3 {
+		t.Errorf("the server has %d events, want 3", got)
+	}
+}
+
+func TestReceiveRejectsInvalidBatches(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.OpenDB(t)
+
+	local, err := activity.LocalDevice(ctx, dbgen.New(db), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		batch Batch
+	}{
+		{"no device", Batch{}},
+		{"own device", Batch{DeviceID: local.ID}},
+		{
+			"decreasing seq",
+			Batch{DeviceID: "laptop", Events: []Event{
+				{Seq: 2, WindowClass: "firefox"},
+				{Seq: 1, WindowClass: "firefox"},
+			}},
+		},
+		{"no class", Batch{DeviceID: "laptop", Events: []Event{{Seq: 1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Receive(ctx, db, tt.batch, time.Now())
+			if !errors.Is(err, ErrInvalidBatch) {
+				t.Errorf("got %v, want ErrInvalidBatch", err)
+			}
+		})
+	}
+}
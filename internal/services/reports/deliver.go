@@ -0,0 +1,120 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+)
+
+// deliveryTimeout limits how long the mail and notification commands can run.
+const deliveryTimeout = time.Minute
+
+// sendMail pipes the report to a sendmail-compatible command, which gets the
+// recipient as its last argument. The Markdown version is the plain-text part
+// of the mail.
+func sendMail(ctx context.Context, command string, to string, r *Report) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return fmt.Errorf("the sendmail command is empty")
+	}
+
+	msg, err := mailMessage(to, r)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], to)...)
+	cmd.Stdin = bytes.NewReader(msg)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(out))
+	}
+
+	return nil
+}
+
+func mailMessage(to string, r *Report) ([]byte, error) {
+	htmlReport, err := r.HTML()
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", []byte(r.Markdown())},
+		{"text/html; charset=utf-8", htmlReport},
+	}
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qw := quotedprintable.NewWriter(pw)
+		if _, err = qw.Write(p.content); err != nil {
+			return nil, err
+		}
+		if err = qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err = mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", r.Title()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", r.GeneratedAt.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// notify shows a desktop notification that links to the report at path.
+func notify(ctx context.Context, title string, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	fileURL := (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case conf.OSLinux, conf.OSFreeBSD:
+		cmd = exec.CommandContext(ctx, "notify-send", "--app-name", conf.ProgramName, title, fileURL)
+	case conf.OSDarwin:
+		script := fmt.Sprintf("display notification %q with title %q", path, title)
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	default:
+		return fmt.Errorf("desktop notifications aren't supported on %s", runtime.GOOS)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(out))
+	}
+
+	return nil
+}
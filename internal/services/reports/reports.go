@@ -0,0 +1,374 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// TopPrograms is how many programs a report lists.
+const TopPrograms = 10
+
+const dateLayout = "2006-01-02"
+
+// Item is the time spent in a program or a category during the period of a
+// report and during the period before it.
+type Item struct {
+	Name         string
+	DurationSecs int64
+	PrevSecs     int64
+}
+
+// Report summarizes a week or a month and compares it with the one before.
+type Report struct {
+	Period string
+	// First and Last are the first and the last day of the period. Start and
+	// End are when it starts and ends, which depends on the hour that days
+	// start at.
+	First     time.Time
+	Last      time.Time
+	Start     time.Time
+	End       time.Time
+	PrevFirst time.Time
+	PrevLast  time.Time
+
+	TotalSecs     int64
+	PrevTotalSecs int64
+	ActiveDays    int
+	Programs      []Item
+	Categories    []Item
+	Focus         activity.FocusStats
+	PrevFocus     activity.FocusStats
+	GeneratedAt   time.Time
+}
+
+// Bounds returns the first day of the period that date is in and the first day
+// of the period after it.
+func Bounds(period string, date time.Time) (first time.Time, next time.Time) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	switch period {
+	case conf.ReportPeriodMonthly:
+		first = day.AddDate(0, 0, 1-day.Day())
+		next = first.AddDate(0, 1, 0)
+	default:
+		daysSinceMonday := (int(day.Weekday()) + 6) % 7
+		first = day.AddDate(0, 0, -daysSinceMonday)
+		next = first.AddDate(0, 0, 7)
+	}
+
+	return first, next
+}
+
+// Build sums up the period that date is in and the period before it.
+func Build(
+	ctx context.Context,
+	q *dbgen.Queries,
+	period string,
+	date time.Time,
+	opts activity.Options,
+	now time.Time,
+) (*Report, error) {
+	if period != conf.ReportPeriodWeekly && period != conf.ReportPeriodMonthly {
+		return nil, fmt.Errorf("%q is not a valid report period (expected one of these values: %v)", period, conf.ReportPeriods)
+	}
+
+	first, next := Bounds(period, date)
+	prevFirst, _ := Bounds(period, first.AddDate(0, 0, -1))
+
+	report := &Report{
+		Period:      period,
+		First:       first,
+		Last:        next.AddDate(0, 0, -1),
+		PrevFirst:   prevFirst,
+		PrevLast:    first.AddDate(0, 0, -1),
+		GeneratedAt: now,
+	}
+	report.Start, _ = activity.GetDayIntervalForDate(first, opts.DayStartHour)
+	report.End, _ = activity.GetDayIntervalForDate(next, opts.DayStartHour)
+	prevStart, _ := activity.GetDayIntervalForDate(prevFirst, opts.DayStartHour)
+
+	current, err := summarize(ctx, q, report.Start, report.End, opts)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := summarize(ctx, q, prevStart, report.Start, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report.TotalSecs = current.totalSecs
+	report.PrevTotalSecs = prev.totalSecs
+	report.ActiveDays = len(current.days)
+	report.Focus = current.focus
+	report.PrevFocus = prev.focus
+	report.Programs = compare(current.programs, prev.programs)
+	if len(report.Programs) > TopPrograms {
+		report.Programs = report.Programs[:TopPrograms]
+	}
+	report.Categories = compare(current.categories, prev.categories)
+
+	return report, nil
+}
+
+type summary struct {
+	totalSecs  int64
+	days       map[string]bool
+	programs   map[string]int64
+	categories map[string]int64
+	focus      activity.FocusStats
+}
+
+func summarize(ctx context.Context, q *dbgen.Queries, start time.Time, end time.Time, opts activity.Options) (*summary, error) {
+	segments, err := activity.GetTimeline(ctx, q, start, end, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &summary{
+		days:       make(map[string]bool),
+		programs:   make(map[string]int64),
+		categories: make(map[string]int64),
+		focus:      activity.GetFocusStats(segments),
+	}
+	for _, seg := range segments {
+		secs := seg.DurationSecs()
+		if secs <= 0 {
+			continue
+		}
+
+		category := seg.Category
+		if category == "" {
+			category = activity.UncategorizedName
+		}
+
+		// Days start at DayStartHour, so time after midnight counts towards
+		// the day before.
+		day := seg.Start.Add(-time.Duration(opts.DayStartHour) * time.Hour)
+
+		s.totalSecs += secs
+		s.days[day.Format(dateLayout)] = true
+		s.programs[seg.Label] += secs
+		s.categories[category] += secs
+	}
+
+	return s, nil
+}
+
+// compare lists everything that time was spent on during the current period,
+// with the most time first.
+func compare(current map[string]int64, prev map[string]int64) []Item {
+	items := make([]Item, 0, len(current))
+	for name, secs := range current {
+		items = append(items, Item{Name: name, DurationSecs: secs, PrevSecs: prev[name]})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].DurationSecs != items[j].DurationSecs {
+			return items[i].DurationSecs > items[j].DurationSecs
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	return items
+}
+
+// Title is the heading of the report, e.g. "Weekly report: 2025-06-02 to
+// 2025-06-08".
+func (r *Report) Title() string {
+	kind := "Weekly"
+	if r.Period == conf.ReportPeriodMonthly {
+		kind = "Monthly"
+	}
+
+	return fmt.Sprintf("%s report: %s to %s", kind, r.First.Format(dateLayout), r.Last.Format(dateLayout))
+}
+
+// FileName is the name of the report's files without the extension, e.g.
+// "weekly-2025-06-02" or "monthly-2025-06".
+func (r *Report) FileName() string {
+	return fileName(r.Period, r.First)
+}
+
+func fileName(period string, first time.Time) string {
+	if period == conf.ReportPeriodMonthly {
+		return "monthly-" + first.Format("2006-01")
+	}
+
+	return "weekly-" + first.Format(dateLayout)
+}
+
+// Markdown renders the report.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", r.Title())
+	fmt.Fprintf(
+		&b,
+		"Compared with %s to %s. Generated on %s.\n\n",
+		r.PrevFirst.Format(dateLayout),
+		r.PrevLast.Format(dateLayout),
+		r.GeneratedAt.Format("2006-01-02 15:04"),
+	)
+
+	b.WriteString("## Totals\n\n")
+	b.WriteString("| | This period | Previous period | Change |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	writeRow(&b, "Screen time", r.TotalSecs, r.PrevTotalSecs)
+	writeRow(&b, "Focus time", r.Focus.FocusSecs, r.PrevFocus.FocusSecs)
+	writeRow(&b, "Longest session", r.Focus.LongestSessionSecs, r.PrevFocus.LongestSessionSecs)
+	fmt.Fprintf(
+		&b,
+		"| Focus sessions | %d | %d | %s |\n",
+		r.Focus.FocusSessions,
+		r.PrevFocus.FocusSessions,
+		formatChange(int64(r.Focus.FocusSessions), int64(r.PrevFocus.FocusSessions)),
+	)
+	fmt.Fprintf(
+		&b,
+		"| Context switches | %d | %d | %s |\n",
+		r.Focus.ContextSwitches,
+		r.PrevFocus.ContextSwitches,
+		formatChange(int64(r.Focus.ContextSwitches), int64(r.PrevFocus.ContextSwitches)),
+	)
+	fmt.Fprintf(&b, "| Active days | %d | | |\n", r.ActiveDays)
+	if r.ActiveDays > 0 {
+		fmt.Fprintf(&b, "| Daily average | %s | | |\n", formatDuration(r.TotalSecs/int64(r.ActiveDays)))
+	}
+	fmt.Fprintf(
+		&b,
+		"\nFocus time is the time spent in sessions of at least %d minutes in a single program.\n",
+		int(activity.MinFocusSession.Minutes()),
+	)
+
+	writeItems(&b, "Top programs", "Program", r.Programs)
+	writeItems(&b, "Categories", "Category", r.Categories)
+
+	return b.String()
+}
+
+func writeRow(b *strings.Builder, name string, secs int64, prevSecs int64) {
+	fmt.Fprintf(b, "| %s | %s | %s | %s |\n", name, formatDuration(secs), formatDuration(prevSecs), formatChange(secs, prevSecs))
+}
+
+func writeItems(b *strings.Builder, heading string, column string, items []Item) {
+	fmt.Fprintf(b, "\n## %s\n\n", heading)
+	if len(items) == 0 {
+		b.WriteString("Nothing was recorded.\n")
+		return
+	}
+
+	fmt.Fprintf(b, "| %s | Time | Previous period | Change |\n", column)
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, item := range items {
+		fmt.Fprintf(
+			b,
+			"| %s | %s | %s | %s |\n",
+			escapeCell(item.Name),
+			formatDuration(item.DurationSecs),
+			formatDuration(item.PrevSecs),
+			formatChange(item.DurationSecs, item.PrevSecs),
+		)
+	}
+}
+
+var markdown = goldmark.New(goldmark.WithExtensions(extension.Table))
+
+// HTML renders the report as a standalone page.
+func (r *Report) HTML() ([]byte, error) {
+	var body bytes.Buffer
+	if err := markdown.Convert([]byte(r.Markdown()), &body); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString("<!doctype html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s | telltime</title>\n", html.EscapeString(r.Title()))
+	b.WriteString("<style>\n")
+	b.WriteString("body { font-family: sans-serif; max-width: 60rem; margin: 2rem auto; padding: 0 1rem; }\n")
+	b.WriteString("table { border-collapse: collapse; margin-bottom: 1rem; }\n")
+	b.WriteString("th, td { border: 1px solid #ccc; padding: 0.25rem 0.75rem; text-align: left; }\n")
+	b.WriteString("</style>\n</head>\n<body>\n")
+	b.Write(body.Bytes())
+	b.WriteString("</body>\n</html>\n")
+
+	return b.Bytes(), nil
+}
+
+// Write writes the Markdown and the HTML version of the report to dir and
+// returns the path of the HTML file.
+func Write(r *Report, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	htmlReport, err := r.HTML()
+	if err != nil {
+		return "", err
+	}
+
+	base := filepath.Join(dir, r.FileName())
+	if err = os.WriteFile(base+".md", []byte(r.Markdown()), 0600); err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(base+".html", htmlReport, 0600); err != nil {
+		return "", err
+	}
+
+	return base + ".html", nil
+}
+
+// exists reports whether the report that would be written to dir as fileName
+// is already there.
+func exists(dir string, fileName string) (bool, error) {
+	_, err := os.Stat(filepath.Join(dir, fileName+".html"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// formatDuration formats secs in hours and minutes, e.g. "3h 25m".
+func formatDuration(secs int64) string {
+	hours, mins := secs/3600, (secs%3600)/60
+	if hours == 0 {
+		return fmt.Sprintf("%dm", mins)
+	}
+
+	return fmt.Sprintf("%dh %dm", hours, mins)
+}
+
+// formatChange describes how much prev changed to get to current, e.g.
+// "+12%".
+func formatChange(current int64, prev int64) string {
+	switch {
+	case prev == 0 && current == 0:
+		return "-"
+	case prev == 0:
+		return "new"
+	}
+
+	percent := (current - prev) * 100 / prev
+	if percent > 0 {
+		return fmt.Sprintf("+%d%%", percent)
+	}
+
+	return fmt.Sprintf("%d%%", percent)
+}
+
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
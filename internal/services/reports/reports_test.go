@@ -0,0 +1,201 @@
+package reports
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+func insertEvent(t *testing.T, q *dbgen.Queries, start time.Time, class string, duration time.Duration) {
+	t.Helper()
+
+	err := q.InsertEvents(context.Background(), dbgen.InsertEventsParams{
+		StartTime:   start.Unix(),
+		WindowClass: class,
+		Duration:    int64(duration.Seconds()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func date(s string) time.Time {
+	t, _ := time.ParseInLocation(dateLayout, s, time.Local)
+	return t
+}
+
+func TestBounds(t *testing.T) {
+	tests := []struct {
+		period    string
+		date      string
+		wantFirst string
+		wantNext  string
+	}{
+		{conf.ReportPeriodWeekly, "2025-06-04", "2025-06-02", "2025-06-09"},
+		{conf.ReportPeriodWeekly, "2025-06-08", "2025-06-02", "2025-06-09"},
+		{conf.ReportPeriodMonthly, "2025-06-30", "2025-06-01", "2025-07-01"},
+		{conf.ReportPeriodMonthly, "2025-12-15", "2025-12-01", "2026-01-01"},
+	}
+
+	for _, tt := range tests {
+		first, next := Bounds(tt.period, date(tt.date))
+		if first.Format(dateLayout) != tt.wantFirst || next.Format(dateLayout) != tt.wantNext {
+			t.Errorf("%s %s: got %s to %s, want %s to %s", tt.period, tt.date, first.Format(dateLayout), next.Format(dateLayout), tt.wantFirst, tt.wantNext)
+		}
+	}
+}
+
+func TestLatestDue(t *testing.T) {
+	monday := date("2025-06-09")
+
+	tests := []struct {
+		name         string
+		period       string
+		now          time.Time
+		dayStartHour int
+		want         string
+	}{
+		{"before the report hour", conf.ReportPeriodWeekly, monday.Add(7 * time.Hour), 0, "2025-05-26"},
+		{"after the report hour", conf.ReportPeriodWeekly, monday.Add(9 * time.Hour), 0, "2025-06-02"},
+		{"before the day is over", conf.ReportPeriodWeekly, monday.Add(9 * time.Hour), 10, "2025-05-26"},
+		{"monthly", conf.ReportPeriodMonthly, date("2025-06-14"), 0, "2025-05-01"},
+	}
+
+	for _, tt := range tests {
+		got := latestDue(tt.period, tt.now, tt.dayStartHour, 8)
+		if got.Format(dateLayout) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got.Format(dateLayout), tt.want)
+		}
+	}
+}
+
+func TestBuildComparesWithThePreviousPeriod(t *testing.T) {
+	ctx := context.Background()
+	q := dbgen.New(testutil.OpenDB(t))
+
+	insertEvent(t, q, date("2025-05-27").Add(9*time.Hour), "Code", time.Hour)
+	insertEvent(t, q, date("2025-06-03").Add(9*time.Hour), "Code", 90*time.Minute)
+	insertEvent(t, q, date("2025-06-04").Add(9*time.Hour), "firefox", 30*time.Minute)
+
+	opts := activity.Options{Categories: map[string]string{"Code": "Work"}}
+	report, err := Build(ctx, q, conf.ReportPeriodWeekly, date("2025-06-05"), opts, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.TotalSecs != 2*60*60 || report.PrevTotalSecs != 60*60 {
+		t.Errorf("got totals of %ds and %ds, want 7200s and 3600s", report.TotalSecs, report.PrevTotalSecs)
+	}
+	if report.ActiveDays != 2 {
+		t.Errorf("got %d active days, want 2", report.ActiveDays)
+	}
+	if report.Focus.FocusSessions != 2 || report.Focus.LongestSessionSecs != 90*60 {
+		t.Errorf("got focus stats %+v", report.Focus)
+	}
+
+	wantPrograms := []Item{{"Code", 90 * 60, 60 * 60}, {"firefox", 30 * 60, 0}}
+	if len(report.Programs) != 2 || report.Programs[0] != wantPrograms[0] || report.Programs[1] != wantPrograms[1] {
+		t.Errorf("got programs %+v, want %+v", report.Programs, wantPrograms)
+	}
+
+	md := report.Markdown()
+	for _, want := range []string{
+		"# Weekly report: 2025-06-02 to 2025-06-08",
+		"| Screen time | 2h 0m | 1h 0m | +100% |",
+		"| Code | 1h 30m | 1h 0m | +50% |",
+		"| firefox | 30m | 0m | new |",
+		"| Work | 1h 30m | 1h 0m | +50% |",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("the report doesn't contain %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestWriteIfDueWritesOnce(t *testing.T) {
+	ctx := context.Background()
+	q := dbgen.New(testutil.OpenDB(t))
+	config := &conf.Config{ReportsDir: t.TempDir(), ReportHour: 8}
+	now := date("2025-06-09").Add(9 * time.Hour)
+
+	insertEvent(t, q, date("2025-06-03").Add(9*time.Hour), "Code", time.Hour)
+
+	report, path, err := writeIfDue(ctx, q, config, activity.Options{}, conf.ReportPeriodWeekly, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report == nil || path != filepath.Join(config.ReportsDir, "weekly-2025-06-02.html") {
+		t.Fatalf("got %q, want the report of the week of 2025-06-02", path)
+	}
+	if _, err = os.Stat(filepath.Join(config.ReportsDir, "weekly-2025-06-02.md")); err != nil {
+		t.Error(err)
+	}
+
+	report, _, err = writeIfDue(ctx, q, config, activity.Options{}, conf.ReportPeriodWeekly, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report != nil {
+		t.Error("the report was written again")
+	}
+}
+
+func TestMailMessage(t *testing.T) {
+	report := &Report{
+		Period:      conf.ReportPeriodWeekly,
+		First:       date("2025-06-02"),
+		Last:        date("2025-06-08"),
+		GeneratedAt: date("2025-06-09"),
+	}
+
+	raw, err := mailMessage("me@example.com", report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("Subject"); got != report.Title() {
+		t.Errorf("got subject %q, want %q", got, report.Title())
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("got content type %q (%v)", mediaType, err)
+	}
+
+	var types []string
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(part)
+		if !strings.Contains(string(body), report.Title()) {
+			t.Errorf("the %s part doesn't contain the title", part.Header.Get("Content-Type"))
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+	}
+
+	if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+		t.Errorf("got parts %v, want plain text and HTML", types)
+	}
+}
@@ -0,0 +1,123 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/activity"
+)
+
+// checkInterval is how often Run checks whether a report is due.
+const checkInterval = 10 * time.Minute
+
+// Run writes a report for every period in config.ReportPeriods once the period
+// is over, and delivers it as configured, until ctx is canceled. Reports that
+// are due but weren't written, e.g. because the computer was off, are written
+// when Run starts. Only the latest period is caught up on. Changes to the
+// settings, such as the categories, apply to the reports written after them.
+func Run(ctx context.Context, db *sql.DB, startupConfig *conf.Config) {
+	q := dbgen.New(db)
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		config := activity.CurrentConfig(startupConfig)
+		opts := activity.OptionsFromConfig(config)
+
+		for _, period := range config.ReportPeriods {
+			report, path, err := writeIfDue(ctx, q, config, opts, period, time.Now())
+			if err != nil && ctx.Err() == nil {
+				slog.Warn("failed to write the report", "period", period, "err", err)
+				continue
+			} else if report == nil {
+				continue
+			}
+
+			slog.Info("wrote a report", "period", period, "path", path)
+			if err = deliver(ctx, config, report, path); err != nil {
+				slog.Warn("failed to deliver the report", "path", path, "err", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeIfDue writes the report for the latest period whose report is due if
+// it hasn't been written yet and anything was recorded during it. It returns
+// the report and the path of its HTML file, or nil if nothing was written.
+func writeIfDue(
+	ctx context.Context,
+	q *dbgen.Queries,
+	config *conf.Config,
+	opts activity.Options,
+	period string,
+	now time.Time,
+) (*Report, string, error) {
+	first := latestDue(period, now, config.DayStartHour, config.ReportHour)
+
+	written, err := exists(config.ReportsDir, fileName(period, first))
+	if err != nil || written {
+		return nil, "", err
+	}
+
+	report, err := Build(ctx, q, period, first, opts, now)
+	if err != nil || report.TotalSecs == 0 {
+		return nil, "", err
+	}
+
+	path, err := Write(report, config.ReportsDir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return report, path, nil
+}
+
+// latestDue returns the first day of the latest period whose report is due.
+// A report is due at reportHour on the day after the period, but not before
+// the last day of the period is over.
+func latestDue(period string, now time.Time, dayStartHour int, reportHour int) time.Time {
+	current, _ := Bounds(period, now)
+	first, _ := Bounds(period, current.AddDate(0, 0, -1))
+
+	due := time.Date(current.Year(), current.Month(), current.Day(), reportHour, 0, 0, 0, current.Location())
+	// The last day of the period is over once the current period starts.
+	if start, _ := activity.GetDayIntervalForDate(current, dayStartHour); due.Before(start) {
+		due = start
+	}
+	if now.Before(due) {
+		first, _ = Bounds(period, first.AddDate(0, 0, -1))
+	}
+
+	return first
+}
+
+// deliver mails the report and shows a notification if they're enabled.
+func deliver(ctx context.Context, config *conf.Config, report *Report, path string) error {
+	var errs []error
+
+	if config.ReportSendmail != "" {
+		if err := sendMail(ctx, config.ReportSendmail, config.ReportEmail, report); err != nil {
+			errs = append(errs, fmt.Errorf("mailing: %v", err))
+		}
+	}
+	if config.ReportNotify {
+		if err := notify(ctx, report.Title(), path); err != nil {
+			errs = append(errs, fmt.Errorf("showing a notification: %v", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
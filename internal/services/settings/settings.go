@@ -0,0 +1,225 @@
+package settings
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+)
+
+// Settings are the parts of the configuration that can be edited from the
+// dashboard. Every field is stored as a row of the setting table with its
+// value encoded as JSON. Stored settings take precedence over the flags,
+// which only provide the initial values.
+type Settings struct {
+	WindowCheckInterval int                 `json:"window_check_interval"`
+	SaveInterval        int                 `json:"save_interval"`
+	MinTitleDuration    int                 `json:"min_title_duration"`
+	RecordWindowTitles  bool                `json:"record_window_titles"`
+	ExcludedClasses     []string            `json:"excluded_classes"`
+	DayStartHour        int                 `json:"day_start_hour"`
+	Categories          []conf.CategoryRule `json:"categories"`
+}
+
+// ValidationError maps the names of the settings to what's wrong with them.
+type ValidationError map[string]string
+
+func (e ValidationError) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	problems := make([]string, 0, len(names))
+	for _, name := range names {
+		problems = append(problems, fmt.Sprintf("%s: %s", name, e[name]))
+	}
+
+	return "invalid settings: " + strings.Join(problems, "; ")
+}
+
+func FromConfig(config *conf.Config) Settings {
+	return Settings{
+		WindowCheckInterval: config.WindowCheckInterval,
+		SaveInterval:        config.SaveInterval,
+		MinTitleDuration:    config.MinTitleDuration,
+		RecordWindowTitles:  config.RecordWindowTitles,
+		ExcludedClasses:     config.ExcludedClasses,
+		DayStartHour:        config.DayStartHour,
+		Categories:          config.Categories,
+	}
+}
+
+// Apply copies the settings to config.
+func (s Settings) Apply(config *conf.Config) {
+	config.WindowCheckInterval = s.WindowCheckInterval
+	config.SaveInterval = s.SaveInterval
+	config.MinTitleDuration = s.MinTitleDuration
+	config.RecordWindowTitles = s.RecordWindowTitles
+	config.ExcludedClasses = s.ExcludedClasses
+	config.DayStartHour = s.DayStartHour
+	config.Categories = s.Categories
+}
+
+// Validate returns a ValidationError that lists every invalid setting.
+func (s Settings) Validate() error {
+	errs := make(ValidationError)
+
+	checkRange := func(name string, value int, min int, max int) {
+		if value < min || value > max {
+			errs[name] = fmt.Sprintf("must be between %d and %d", min, max)
+		}
+	}
+	checkRange("window_check_interval", s.WindowCheckInterval, 1, 300)
+	checkRange("save_interval", s.SaveInterval, 10, 24*60*60)
+	checkRange("min_title_duration", s.MinTitleDuration, 0, 60*60)
+	checkRange("day_start_hour", s.DayStartHour, 0, 23)
+
+	for _, class := range s.ExcludedClasses {
+		if strings.TrimSpace(class) == "" {
+			errs["excluded_classes"] = "must not contain empty classes"
+		}
+	}
+
+	categorized := make(map[string]string)
+	for _, rule := range s.Categories {
+		if strings.TrimSpace(rule.Category) == "" {
+			errs["categories"] = "every rule needs a category"
+			break
+		}
+		if len(rule.Classes) == 0 {
+			errs["categories"] = fmt.Sprintf("%q has no window classes", rule.Category)
+			break
+		}
+
+		for _, class := range rule.Classes {
+			if category, ok := categorized[class]; ok && category != rule.Category {
+				errs["categories"] = fmt.Sprintf("%q is in both %q and %q", class, category, rule.Category)
+			}
+			categorized[class] = rule.Category
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// Load applies the stored settings to config. Settings that were never saved
+// keep the values from the flags.
+func Load(ctx context.Context, q *dbgen.Queries, config *conf.Config) error {
+	rows, err := q.GetSettings(ctx)
+	if err != nil {
+		return fmt.Errorf("reading the settings: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	stored := make(map[string]json.RawMessage, len(rows))
+	for _, row := range rows {
+		stored[row.Name] = json.RawMessage(row.Value)
+	}
+
+	// Unmarshaling into the current settings only overwrites the fields
+	// that are stored.
+	b, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	s := FromConfig(config)
+	if err = json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("decoding the settings: %v", err)
+	}
+
+	if err = s.Validate(); err != nil {
+		return err
+	}
+	s.Apply(config)
+
+	return nil
+}
+
+// Save validates the settings and stores all of them.
+func Save(ctx context.Context, db *sql.DB, s Settings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := dbgen.New(tx)
+	now := time.Now().Unix()
+	for name, value := range fields {
+		err := q.UpsertSetting(ctx, dbgen.UpsertSettingParams{
+			Name:      name,
+			Value:     string(value),
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("saving %s: %v", name, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ParseCategories reads category rules written one per line as the category,
+// a colon and comma-separated window classes, e.g. "Work: code, Alacritty".
+func ParseCategories(text string) ([]conf.CategoryRule, error) {
+	var rules []conf.CategoryRule
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		category, rawClasses, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a category, a colon and window classes", i+1)
+		}
+
+		rule := conf.CategoryRule{Category: strings.TrimSpace(category)}
+		for _, class := range strings.Split(rawClasses, ",") {
+			if class = strings.TrimSpace(class); class != "" && !slices.Contains(rule.Classes, class) {
+				rule.Classes = append(rule.Classes, class)
+			}
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// FormatCategories is the inverse of ParseCategories.
+func FormatCategories(rules []conf.CategoryRule) string {
+	lines := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		lines = append(lines, fmt.Sprintf("%s: %s", rule.Category, strings.Join(rule.Classes, ", ")))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,140 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+func validSettings() Settings {
+	return Settings{
+		WindowCheckInterval: 5,
+		SaveInterval:        300,
+		MinTitleDuration:    10,
+		DayStartHour:        4,
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.OpenDB(t)
+	q := dbgen.New(db)
+
+	config := &conf.Config{WindowCheckInterval: 5, SaveInterval: 300, MinTitleDuration: 10, DayStartHour: 4}
+
+	// Nothing is stored yet, so the flags are kept.
+	if err := Load(ctx, q, config); err != nil {
+		t.Fatal(err)
+	}
+	if config.SaveInterval != 300 {
+		t.Fatalf("got SaveInterval=%d, want the value from the flags", config.SaveInterval)
+	}
+
+	s := validSettings()
+	s.SaveInterval = 60
+	s.RecordWindowTitles = true
+	s.ExcludedClasses = []string{"KeePassXC"}
+	s.DayStartHour = 6
+	s.Categories = []conf.CategoryRule{{Category: "Work", Classes: []string{"code", "Alacritty"}}}
+	if err := Save(ctx, db, s); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving twice updates the rows instead of adding new ones.
+	if err := Save(ctx, db, s); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &conf.Config{Port: 8000}
+	if err := Load(ctx, q, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if got := FromConfig(loaded); !reflect.DeepEqual(got, s) {
+		t.Errorf("got %+v, want %+v", got, s)
+	}
+	if loaded.Port != 8000 {
+		t.Errorf("got Port=%d, want it untouched", loaded.Port)
+	}
+}
+
+func TestSaveRejectsInvalidSettings(t *testing.T) {
+	db := testutil.OpenDB(t)
+
+	s := validSettings()
+	s.WindowCheckInterval = 0
+	s.DayStartHour = 24
+
+	err := Save(context.Background(), db, s)
+
+	var validationErr ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got err=%v, want a ValidationError", err)
+	}
+	for _, name := range []string{"window_check_interval", "day_start_hour"} {
+		if _, ok := validationErr[name]; !ok {
+			t.Errorf("%s wasn't reported as invalid", name)
+		}
+	}
+
+	rows, err := dbgen.New(db).GetSettings(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("got %d stored settings, want none", len(rows))
+	}
+}
+
+func TestValidateCategories(t *testing.T) {
+	tests := []struct {
+		name       string
+		categories []conf.CategoryRule
+		wantErr    bool
+	}{
+		{"valid", []conf.CategoryRule{{Category: "Work", Classes: []string{"code"}}, {Category: "Chat", Classes: []string{"Signal"}}}, false},
+		{"no category", []conf.CategoryRule{{Category: "", Classes: []string{"code"}}}, true},
+		{"no classes", []conf.CategoryRule{{Category: "Work", Classes: nil}}, true},
+		{"class in two categories", []conf.CategoryRule{{Category: "Work", Classes: []string{"code"}}, {Category: "Fun", Classes: []string{"code"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := validSettings()
+			s.Categories = tt.categories
+
+			if err := s.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("got err=%v, want an error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCategories(t *testing.T) {
+	text := "Work: code, Alacritty, code\n\n  Chat:Signal  \n"
+
+	rules, err := ParseCategories(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []conf.CategoryRule{
+		{Category: "Work", Classes: []string{"code", "Alacritty"}},
+		{Category: "Chat", Classes: []string{"Signal"}},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("got %+v, want %+v", rules, want)
+	}
+
+	if got := FormatCategories(rules); got != "Work: code, Alacritty\nChat: Signal" {
+		t.Errorf("got %q from FormatCategories", got)
+	}
+
+	if _, err := ParseCategories("Work code"); err == nil {
+		t.Error("got no error for a line without a colon")
+	}
+}
@@ -0,0 +1,442 @@
+package team
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/activity"
+)
+
+// ReportsPath is where the server accepts team reports.
+const ReportsPath = "/api/team/reports"
+
+// ReportVersion is the version of the report format. It's part of the signed
+// document, so reports can't be passed off as a different version.
+const ReportVersion = 1
+
+const (
+	DateLayout = "2006-01-02"
+
+	maxPersonLength   = 255
+	maxCategoryLength = 255
+	maxCategories     = 100
+	publishTimeout    = 30 * time.Second
+	keyPEMType        = "PRIVATE KEY"
+)
+
+var (
+	ErrInvalidReport = errors.New("invalid report")
+	ErrBadSignature  = errors.New("the signature doesn't match the report")
+
+	// ErrKeyMismatch is returned when a report uses a name that another key
+	// already sent reports under, or a key that sent reports under another
+	// name. The first key seen for a name is trusted from then on, so
+	// teammates can't send reports for each other.
+	ErrKeyMismatch = errors.New("the name and the key don't match the earlier reports")
+)
+
+// CategoryTotal is the time spent in a category during the period of a
+// report.
+type CategoryTotal struct {
+	Category     string `json:"category"`
+	DurationSecs int64  `json:"duration_secs"`
+}
+
+// Report is the weekly summary that's shared with the team. It only has the
+// total time per category, never what the time was spent on, so that nothing
+// else leaves the machine. It's signed with the key of the person, which is
+// how the server tells who sent it.
+type Report struct {
+	Version     int             `json:"version"`
+	Person      string          `json:"person"`
+	PeriodStart string          `json:"period_start"`
+	PeriodEnd   string          `json:"period_end"`
+	GeneratedAt int64           `json:"generated_at"`
+	TotalSecs   int64           `json:"total_secs"`
+	Categories  []CategoryTotal `json:"categories"`
+	PublicKey   string          `json:"public_key"`
+	Signature   string          `json:"signature,omitempty"`
+}
+
+// Member is what a person reported for a period.
+type Member struct {
+	Person      string           `json:"person"`
+	TotalSecs   int64            `json:"total_secs"`
+	Categories  map[string]int64 `json:"categories"`
+	GeneratedAt time.Time        `json:"generated_at"`
+}
+
+// Summary combines the reports of everyone for a period. Categories has the
+// totals of the whole team, with the most time spent first.
+type Summary struct {
+	PeriodStart string          `json:"period_start"`
+	PeriodEnd   string          `json:"period_end"`
+	TotalSecs   int64           `json:"total_secs"`
+	Categories  []CategoryTotal `json:"categories"`
+	Members     []*Member       `json:"members"`
+}
+
+// WeekOf returns the first and the last day of the week (from Monday to
+// Sunday) that date is in.
+func WeekOf(date time.Time) (first time.Time, last time.Time) {
+	daysSinceMonday := (int(date.Weekday()) + 6) % 7
+	first = time.Date(date.Year(), date.Month(), date.Day()-daysSinceMonday, 0, 0, 0, 0, date.Location())
+	last = first.AddDate(0, 0, 6)
+
+	return first, last
+}
+
+// BuildReport sums up the time spent in each category during the week that
+// date is in. The report isn't signed yet.
+func BuildReport(
+	ctx context.Context,
+	q *dbgen.Queries,
+	person string,
+	date time.Time,
+	opts activity.Options,
+	now time.Time,
+) (*Report, error) {
+	first, last := WeekOf(date)
+	start, _ := activity.GetDayIntervalForDate(first, opts.DayStartHour)
+	_, end := activity.GetDayIntervalForDate(last, opts.DayStartHour)
+
+	stats, err := activity.GetCategoryStats(ctx, q, start, end, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Version:     ReportVersion,
+		Person:      strings.TrimSpace(person),
+		PeriodStart: first.Format(DateLayout),
+		PeriodEnd:   last.Format(DateLayout),
+		GeneratedAt: now.Unix(),
+		Categories:  []CategoryTotal{},
+	}
+	for _, s := range stats {
+		if s.DurationSecs <= 0 {
+			continue
+		}
+		report.TotalSecs += s.DurationSecs
+		report.Categories = append(report.Categories, CategoryTotal{
+			Category:     s.CategoryName,
+			DurationSecs: s.DurationSecs,
+		})
+	}
+	sortCategories(report.Categories)
+
+	return report, nil
+}
+
+// Sign adds the public key and the signature to r.
+func Sign(r *Report, key ed25519.PrivateKey) error {
+	r.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+
+	msg, err := signedBytes(*r)
+	if err != nil {
+		return err
+	}
+	r.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, msg))
+
+	return nil
+}
+
+// Verify checks that r is valid and that it was signed with its public key.
+func Verify(r *Report) error {
+	if err := validateReport(r); err != nil {
+		return err
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(r.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: public_key isn't a base64-encoded Ed25519 key", ErrInvalidReport)
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: signature isn't base64-encoded", ErrInvalidReport)
+	}
+
+	msg, err := signedBytes(*r)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, msg, signature) {
+		return ErrBadSignature
+	}
+
+	return nil
+}
+
+// signedBytes returns what the signature covers, which is the report without
+// the signature.
+func signedBytes(r Report) ([]byte, error) {
+	r.Signature = ""
+	return json.Marshal(r)
+}
+
+func validateReport(r *Report) error {
+	if r.Version != ReportVersion {
+		return fmt.Errorf("%w: version %d isn't supported (expected %d)", ErrInvalidReport, r.Version, ReportVersion)
+	}
+	if strings.TrimSpace(r.Person) == "" || len(r.Person) > maxPersonLength {
+		return fmt.Errorf("%w: person must have between 1 and %d characters", ErrInvalidReport, maxPersonLength)
+	}
+
+	first, err := time.Parse(DateLayout, r.PeriodStart)
+	if err != nil {
+		return fmt.Errorf("%w: period_start must be a date (format: %q)", ErrInvalidReport, DateLayout)
+	}
+	if first.Weekday() != time.Monday {
+		return fmt.Errorf("%w: period_start must be a Monday", ErrInvalidReport)
+	}
+	if r.PeriodEnd != first.AddDate(0, 0, 6).Format(DateLayout) {
+		return fmt.Errorf("%w: period_end must be the Sunday after period_start", ErrInvalidReport)
+	}
+
+	if len(r.Categories) > maxCategories {
+		return fmt.Errorf("%w: a report can't have more than %d categories", ErrInvalidReport, maxCategories)
+	}
+	var total int64
+	seen := make(map[string]bool, len(r.Categories))
+	for _, c := range r.Categories {
+		if strings.TrimSpace(c.Category) == "" || len(c.Category) > maxCategoryLength {
+			return fmt.Errorf("%w: category names must have between 1 and %d characters", ErrInvalidReport, maxCategoryLength)
+		}
+		if seen[c.Category] {
+			return fmt.Errorf("%w: category %q is listed more than once", ErrInvalidReport, c.Category)
+		}
+		if c.DurationSecs < 0 || c.DurationSecs > 7*24*60*60 {
+			return fmt.Errorf("%w: category %q has an impossible duration", ErrInvalidReport, c.Category)
+		}
+		seen[c.Category] = true
+		total += c.DurationSecs
+	}
+	if total != r.TotalSecs {
+		return fmt.Errorf("%w: total_secs isn't the sum of the categories", ErrInvalidReport)
+	}
+
+	return nil
+}
+
+// LoadOrCreateKey reads the signing key from the file at path. A new key is
+// written to it first if the file doesn't exist. Only the owner can read the
+// file.
+func LoadOrCreateKey(path string) (ed25519.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return createKey(path)
+	} else if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != keyPEMType {
+		return nil, fmt.Errorf("%q doesn't contain a PEM-encoded private key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %v", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%q doesn't contain an Ed25519 key", path)
+	}
+
+	return key, nil
+}
+
+func createKey(path string) (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: keyPEMType, Bytes: der}), 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Publish sends a signed report to the server at serverURL.
+func Publish(ctx context.Context, serverURL string, token string, r *Report) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimRight(strings.TrimSpace(serverURL), "/") + ReportsPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: publishTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	var respBody struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&respBody)
+
+	return fmt.Errorf("the server responded with %q: %s", resp.Status, respBody.Error)
+}
+
+// Store verifies a report sent by a teammate and stores it. A newer report
+// for the same week replaces the older one.
+func Store(ctx context.Context, db *sql.DB, r *Report, now time.Time) error {
+	if err := Verify(r); err != nil {
+		return err
+	}
+
+	document, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := dbgen.New(tx)
+
+	member, err := q.GetTeamMember(ctx, r.PublicKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = q.GetTeamMemberByPerson(ctx, r.Person)
+		if err == nil {
+			return fmt.Errorf("%w: %q sent reports with another key", ErrKeyMismatch, r.Person)
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		err = q.InsertTeamMember(ctx, dbgen.InsertTeamMemberParams{
+			PublicKey:   r.PublicKey,
+			Person:      r.Person,
+			FirstSeenAt: now.Unix(),
+		})
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	} else if member.Person != r.Person {
+		return fmt.Errorf("%w: the key sent reports as %q", ErrKeyMismatch, member.Person)
+	}
+
+	err = q.UpsertTeamReport(ctx, dbgen.UpsertTeamReportParams{
+		PublicKey:   r.PublicKey,
+		PeriodStart: r.PeriodStart,
+		PeriodEnd:   r.PeriodEnd,
+		GeneratedAt: r.GeneratedAt,
+		ReceivedAt:  now.Unix(),
+		Document:    string(document),
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Periods returns the first days of the most recent weeks that reports were
+// received for, the latest first.
+func Periods(ctx context.Context, q *dbgen.Queries, limit int64) ([]string, error) {
+	return q.GetTeamReportPeriods(ctx, limit)
+}
+
+// Combine adds up the reports received for the week that starts on
+// periodStart.
+func Combine(ctx context.Context, q *dbgen.Queries, periodStart string) (*Summary, error) {
+	rows, err := q.GetTeamReports(ctx, periodStart)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		PeriodStart: periodStart,
+		Categories:  []CategoryTotal{},
+		Members:     []*Member{},
+	}
+	if first, err := time.Parse(DateLayout, periodStart); err == nil {
+		summary.PeriodEnd = first.AddDate(0, 0, 6).Format(DateLayout)
+	}
+
+	totals := make(map[string]int64)
+	for _, row := range rows {
+		var r Report
+		if err := json.Unmarshal([]byte(row.Document), &r); err != nil {
+			return nil, fmt.Errorf("reading the report of %q: %v", row.PublicKey, err)
+		}
+
+		member := &Member{
+			Person:      r.Person,
+			TotalSecs:   r.TotalSecs,
+			Categories:  make(map[string]int64, len(r.Categories)),
+			GeneratedAt: time.Unix(r.GeneratedAt, 0),
+		}
+		for _, c := range r.Categories {
+			member.Categories[c.Category] = c.DurationSecs
+			totals[c.Category] += c.DurationSecs
+		}
+
+		summary.TotalSecs += r.TotalSecs
+		summary.Members = append(summary.Members, member)
+	}
+
+	for name, secs := range totals {
+		summary.Categories = append(summary.Categories, CategoryTotal{Category: name, DurationSecs: secs})
+	}
+	sortCategories(summary.Categories)
+	sort.Slice(summary.Members, func(i, j int) bool {
+		return strings.ToLower(summary.Members[i].Person) < strings.ToLower(summary.Members[j].Person)
+	})
+
+	return summary, nil
+}
+
+func sortCategories(categories []CategoryTotal) {
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].DurationSecs != categories[j].DurationSecs {
+			return categories[i].DurationSecs > categories[j].DurationSecs
+		}
+		return categories[i].Category < categories[j].Category
+	})
+}
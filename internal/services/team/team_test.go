@@ -0,0 +1,217 @@
+package team
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bnuredini/telltime/internal/dbgen"
+	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/testutil"
+)
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func signedReport(t *testing.T, key ed25519.PrivateKey, person string, categories ...CategoryTotal) *Report {
+	t.Helper()
+
+	r := &Report{
+		Version:     ReportVersion,
+		Person:      person,
+		PeriodStart: "2025-06-02",
+		PeriodEnd:   "2025-06-08",
+		GeneratedAt: 1000,
+		Categories:  categories,
+	}
+	for _, c := range categories {
+		r.TotalSecs += c.DurationSecs
+	}
+	if err := Sign(r, key); err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func TestWeekOf(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2025-06-02", "2025-06-02"},
+		{"2025-06-05", "2025-06-02"},
+		{"2025-06-08", "2025-06-02"},
+		{"2025-06-09", "2025-06-09"},
+	}
+
+	for _, tt := range tests {
+		date, _ := time.Parse(DateLayout, tt.date)
+		first, last := WeekOf(date)
+		if got := first.Format(DateLayout); got != tt.want {
+			t.Errorf("WeekOf(%s) starts on %s, want %s", tt.date, got, tt.want)
+		}
+		if last.Sub(first) != 6*24*time.Hour {
+			t.Errorf("WeekOf(%s) ends on %s", tt.date, last.Format(DateLayout))
+		}
+	}
+}
+
+func TestBuildReportOnlyHasCategoryTotals(t *testing.T) {
+	ctx := context.Background()
+	q := dbgen.New(testutil.OpenDB(t))
+	base := time.Date(2025, 6, 3, 9, 0, 0, 0, time.Local)
+
+	for _, e := range []dbgen.InsertEventsParams{
+		{StartTime: base.Unix(), WindowClass: "Code", WindowTitle: sql.NullString{String: "secret.go", Valid: true}, Duration: 3600},
+		{StartTime: base.Add(time.Hour).Unix(), WindowClass: "firefox", Duration: 1800},
+		{StartTime: base.AddDate(0, 0, 7).Unix(), WindowClass: "Code", Duration: 600},
+	} {
+		if err := q.InsertEvents(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := activity.Options{Categories: map[string]string{"Code": "Work"}}
+	report, err := BuildReport(ctx, q, "alice", base, opts, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []CategoryTotal{
+		{Category: "Work", DurationSecs: 3600},
+		{Category: activity.UncategorizedName, DurationSecs: 1800},
+	}
+	if len(report.Categories) != len(want) {
+		t.Fatalf("got %+v, want %+v", report.Categories, want)
+	}
+	for i := range want {
+		if report.Categories[i] != want[i] {
+			t.Errorf("category %d: got %+v, want %+v", i, report.Categories[i], want[i])
+		}
+	}
+	if report.TotalSecs != 5400 || report.PeriodStart != "2025-06-02" || report.PeriodEnd != "2025-06-08" {
+		t.Errorf("got %+v", report)
+	}
+}
+
+func TestVerifyDetectsChanges(t *testing.T) {
+	key := newKey(t)
+
+	r := signedReport(t, key, "alice", CategoryTotal{"Work", 3600})
+	if err := Verify(r); err != nil {
+		t.Fatalf("the signed report doesn't verify: %v", err)
+	}
+
+	changed := *r
+	changed.Categories = []CategoryTotal{{"Work", 7200}}
+	changed.TotalSecs = 7200
+	if err := Verify(&changed); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("changed totals: got %v, want ErrBadSignature", err)
+	}
+
+	changed = *r
+	changed.Person = "bob"
+	if err := Verify(&changed); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("changed person: got %v, want ErrBadSignature", err)
+	}
+
+	changed = *r
+	changed.TotalSecs = 1
+	if err := Verify(&changed); !errors.Is(err, ErrInvalidReport) {
+		t.Errorf("wrong total: got %v, want ErrInvalidReport", err)
+	}
+}
+
+func TestStoreAndCombine(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.OpenDB(t)
+	q := dbgen.New(db)
+	now := time.Now()
+
+	alice, bob := newKey(t), newKey(t)
+
+	reports := []*Report{
+		signedReport(t, alice, "alice", CategoryTotal{"Work", 3600}, CategoryTotal{"Meetings", 600}),
+		signedReport(t, bob, "bob", CategoryTotal{"Work", 1800}),
+	}
+	for _, r := range reports {
+		if err := Store(ctx, db, r, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A newer report for the same week replaces the older one.
+	newer := &Report{
+		Version:     ReportVersion,
+		Person:      "bob",
+		PeriodStart: "2025-06-02",
+		PeriodEnd:   "2025-06-08",
+		GeneratedAt: 2000,
+		TotalSecs:   2400,
+		Categories:  []CategoryTotal{{"Work", 2400}},
+	}
+	if err := Sign(newer, bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := Store(ctx, db, newer, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nobody else can send reports as alice, and the key of bob can't be used
+	// under another name.
+	if err := Store(ctx, db, signedReport(t, newKey(t), "alice"), now); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("report for alice with another key: got %v, want ErrKeyMismatch", err)
+	}
+	if err := Store(ctx, db, signedReport(t, bob, "carol"), now); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("report for carol with the key of bob: got %v, want ErrKeyMismatch", err)
+	}
+
+	summary, err := Combine(ctx, q, "2025-06-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(summary.Members) != 2 || summary.Members[0].Person != "alice" || summary.Members[1].Person != "bob" {
+		t.Fatalf("got members %+v, want alice and bob", summary.Members)
+	}
+	if got := summary.Members[1].Categories["Work"]; got != 2400 {
+		t.Errorf("bob has %ds of Work, want the 2400s of the newer report", got)
+	}
+	if summary.TotalSecs != 6600 {
+		t.Errorf("got a total of %ds, want 6600s", summary.TotalSecs)
+	}
+	if summary.Categories[0] != (CategoryTotal{"Work", 6000}) || summary.Categories[1] != (CategoryTotal{"Meetings", 600}) {
+		t.Errorf("got team categories %+v", summary.Categories)
+	}
+}
+
+func TestLoadOrCreateKeyKeepsTheKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team-key")
+
+	created, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !created.Equal(loaded) {
+		t.Error("the loaded key differs from the created one")
+	}
+}
@@ -0,0 +1,138 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/bnuredini/telltime/internal/conf"
+	"github.com/bnuredini/telltime/ui"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+const docsGlob = "docs/*.md"
+
+// DocIndex is the doc shown at /docs.
+const DocIndex = "index"
+
+// Doc is a documentation page. Docs are written in Markdown and converted to
+// HTML once when the templates are loaded.
+type Doc struct {
+	Name  string
+	Title string
+	HTML  template.HTML
+}
+
+// generatedDocs append generated Markdown to the docs with the same name.
+var generatedDocs = map[string]func() (string, error){
+	"configuration": configReference,
+}
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.Table),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+// newDocs converts the Markdown files in docsGlob. The returned list is used
+// for navigation and is sorted by title with the index first.
+func newDocs() (map[string]*Doc, []*Doc, error) {
+	filePaths, err := fs.Glob(ui.Files, docsGlob)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	docs := make(map[string]*Doc, len(filePaths))
+	list := make([]*Doc, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		source, err := fs.ReadFile(ui.Files, filePath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		name := cacheKeyFromPath(filePath)
+		if generate, ok := generatedDocs[name]; ok {
+			generated, err := generate()
+			if err != nil {
+				return nil, nil, fmt.Errorf("generating the %s doc: %v", name, err)
+			}
+			source = append(source, "\n"+generated...)
+		}
+
+		doc, err := newDoc(name, source)
+		if err != nil {
+			return nil, nil, fmt.Errorf("converting %s: %v", filePath, err)
+		}
+		docs[name] = doc
+		list = append(list, doc)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name == DocIndex || list[j].Name == DocIndex {
+			return list[i].Name == DocIndex
+		}
+		return list[i].Title < list[j].Title
+	})
+
+	return docs, list, nil
+}
+
+// newDoc converts source to HTML. The title is taken from the first level one
+// heading and falls back to the name.
+func newDoc(name string, source []byte) (*Doc, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert(source, &buf); err != nil {
+		return nil, err
+	}
+
+	doc := &Doc{
+		Name:  name,
+		Title: name,
+		HTML:  template.HTML(buf.String()),
+	}
+	for _, line := range strings.Split(string(source), "\n") {
+		if title, ok := strings.CutPrefix(line, "# "); ok {
+			doc.Title = strings.TrimSpace(title)
+			break
+		}
+	}
+
+	return doc, nil
+}
+
+// configReference lists the command-line flags as a Markdown table, so that
+// the reference can't get out of date.
+func configReference() (string, error) {
+	options, err := conf.Reference()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("| Flag | Default | Description |\n")
+	b.WriteString("| --- | --- | --- |\n")
+	for _, option := range options {
+		defaultValue := ""
+		if option.Default != "" {
+			defaultValue = "`" + option.Default + "`"
+		}
+
+		fmt.Fprintf(
+			&b,
+			"| `-%s` | %s | %s |\n",
+			option.Flag,
+			escapeTableCell(defaultValue),
+			escapeTableCell(option.Usage),
+		)
+	}
+
+	return b.String(), nil
+}
+
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -1,6 +1,7 @@
 package templates
 
 import (
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"os"
@@ -24,6 +25,9 @@ var tmplFuncs = template.FuncMap{
 
 		return result
 	},
+	"list": func(items ...string) []string {
+		return items
+	},
 	"contains": func(slice []string, item string) bool {
 		return slices.Contains(slice, item)
 	},
@@ -34,6 +38,12 @@ var tmplFuncs = template.FuncMap{
 
 		return t.Format("2006-01-02 15:04")
 	},
+	"formatTime": func(t time.Time, layout string) string {
+		return t.Format(layout)
+	},
+	"unixTime": func(secs int64) time.Time {
+		return time.Unix(secs, 0)
+	},
 	"add": func(values ...int) int {
 		sum := 0
 		for _, val := range values {
@@ -66,6 +76,12 @@ var tmplFuncs = template.FuncMap{
 	"safeHTML": func(s string) template.HTML {
 		return template.HTML(s)
 	},
+	// json is meant for attributes like hx-vals. The result gets escaped like
+	// any other attribute value, which the browser undoes.
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
 	"map": func(pairs ...any) (map[string]any, error) {
 		if len(pairs)%2 != 0 {
 			return nil, os.ErrInvalid
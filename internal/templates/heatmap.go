@@ -0,0 +1,126 @@
+package templates
+
+import "time"
+
+// HeatmapLevels is how many shades the heatmaps use for time spent. Level 0
+// means no time at all.
+const HeatmapLevels = 4
+
+// HeatmapData is what the heatmap page shows: the days of a year and the hours
+// of the weekdays in a range, both limited to a program or a category.
+type HeatmapData struct {
+	Year  *YearHeatmap
+	Hours *HourHeatmap
+
+	Program    string
+	Category   string
+	Programs   []string
+	Categories []string
+
+	// RangeStart and RangeEnd are the first and the last day of the range
+	// (format: "2006-01-02").
+	RangeStart string
+	RangeEnd   string
+}
+
+type HeatmapCell struct {
+	// Date is set for the days of the year heatmap.
+	Date  string
+	Label string
+	Secs  int64
+	Level int
+}
+
+// YearHeatmap has a cell for every day of a year. The cells are laid out in
+// columns of weeks starting on Monday, so GapDays is how many rows the first
+// column skips.
+type YearHeatmap struct {
+	Year      int
+	GapDays   int
+	Days      []HeatmapCell
+	TotalSecs int64
+}
+
+type HourHeatmapRow struct {
+	Weekday string
+	Cells   []HeatmapCell
+}
+
+// HourHeatmap has a row for every weekday, starting on Monday, and a cell for
+// every hour of the day.
+type HourHeatmap struct {
+	Rows      []HourHeatmapRow
+	TotalSecs int64
+}
+
+// NewYearHeatmap lays out the daily seconds of year, which are keyed by date
+// (format: "2006-01-02").
+func NewYearHeatmap(year int, daily map[string]int64) *YearHeatmap {
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	next := first.AddDate(1, 0, 0)
+
+	heatmap := &YearHeatmap{
+		Year:    year,
+		GapDays: (int(first.Weekday()) + 6) % 7,
+	}
+
+	var maxSecs int64
+	for day := first; day.Before(next); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		secs := daily[date]
+
+		maxSecs = max(maxSecs, secs)
+		heatmap.TotalSecs += secs
+		heatmap.Days = append(heatmap.Days, HeatmapCell{
+			Date:  date,
+			Label: date + ": " + formatSecs(secs),
+			Secs:  secs,
+		})
+	}
+
+	// The shades are relative to the busiest day, which is only known once
+	// every day has been summed up.
+	for i := range heatmap.Days {
+		heatmap.Days[i].Level = heatmapLevel(heatmap.Days[i].Secs, maxSecs)
+	}
+
+	return heatmap
+}
+
+// NewHourHeatmap lays out the seconds per weekday (starting on Monday) and hour
+// of the day.
+func NewHourHeatmap(grid [7][24]int64) *HourHeatmap {
+	var maxSecs int64
+	for _, hours := range grid {
+		for _, secs := range hours {
+			maxSecs = max(maxSecs, secs)
+		}
+	}
+
+	heatmap := &HourHeatmap{}
+	for i, hours := range grid {
+		weekday := time.Weekday((i + 1) % 7).String()
+		row := HourHeatmapRow{Weekday: weekday[:3]}
+		for hour, secs := range hours {
+			heatmap.TotalSecs += secs
+			row.Cells = append(row.Cells, HeatmapCell{
+				Label: weekday + " " + time.Date(0, 1, 1, hour, 0, 0, 0, time.UTC).Format("15:04") + ": " + formatSecs(secs),
+				Secs:  secs,
+				Level: heatmapLevel(secs, maxSecs),
+			})
+		}
+		heatmap.Rows = append(heatmap.Rows, row)
+	}
+
+	return heatmap
+}
+
+// heatmapLevel maps secs to a shade relative to the most time spent in a cell.
+func heatmapLevel(secs int64, maxSecs int64) int {
+	if secs <= 0 || maxSecs <= 0 {
+		return 0
+	}
+
+	level := int((secs*HeatmapLevels + maxSecs - 1) / maxSecs)
+	return min(level, HeatmapLevels)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"net/http"
 	"path/filepath"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/bnuredini/telltime/internal/dbgen"
 	"github.com/bnuredini/telltime/internal/services/activity"
+	"github.com/bnuredini/telltime/internal/services/team"
 	"github.com/bnuredini/telltime/ui"
 )
 
@@ -24,15 +26,21 @@ const (
 
 const (
 	Page404      PageName = "404"
+	Page405      PageName = "405"
 	Page500      PageName = "500"
+	PageError    PageName = "error"
 	PageHome     PageName = "home"
 	PageSettings PageName = "settings"
+	PageLogin    PageName = "login"
 	PageActivity PageName = "activity"
 	PageDocs     PageName = "docs"
+	PageTeam     PageName = "team"
+	PageHeatmap  PageName = "heatmap"
 )
 
 type Data struct {
-	WindowChangeEvents []dbgen.GetEventsRow
+	Timeline           []*activity.Segment
+	EventEdits         []dbgen.EventEdit
 	CategoryStats      []*activity.CategoryStat
 	ProgramStats       []*activity.ProgramStat
 	CurrentDateLabel   string
@@ -44,15 +52,82 @@ type Data struct {
 	MostUsedProgram    string
 	TopCategory        string
 	CalendarData       *CalendarData
+	Heatmap            *HeatmapData
 	Temp               time.Time
 	SelectedDate       string
 	OrderBy            string
 	OrderDirection     string
+	GroupBy            string
+	Devices            []dbgen.Device
+	DeviceNames        map[string]string
+	SelectedDevice     string
+	PrevDate           string
+	NextDate           string
+	FormError          string
+	LiveStatus         *LiveStatus
+	CodingStats        *activity.CodingStats
+	ShellStats         *activity.ShellStats
+	SettingsForm       *SettingsForm
+	LoginForm          *LoginForm
+	Doc                *Doc
+	Docs               []*Doc
+	TeamSummary        *team.Summary
+	TeamPeriods        []string
+	AcceptTeamReports  bool
+	Error              *ErrorInfo
+}
+
+// ErrorInfo is what the error pages show about the failed request.
+type ErrorInfo struct {
+	Status     int
+	StatusText string
+	Message    string
+	RequestID  string
+	Method     string
+	Path       string
+}
+
+// SettingsForm holds the values shown on the settings page. They're kept as
+// strings so that invalid input can be shown back as it was entered.
+type SettingsForm struct {
+	WindowCheckInterval string
+	SaveInterval        string
+	MinTitleDuration    string
+	RecordWindowTitles  bool
+	ExcludedClasses     string
+	DayStartHour        string
+	Categories          string
+
+	// Errors maps the names of the fields to what's wrong with them.
+	Errors map[string]string
+	Saved  bool
+
+	// AuthEnabled shows the button for logging out.
+	AuthEnabled bool
+}
+
+type LoginForm struct {
+	// Next is where to go after logging in.
+	Next  string
+	Error string
+}
+
+// LiveStatus is what the home page shows about the current window. It's
+// rendered on the first page load and then pushed over SSE on every change.
+type LiveStatus struct {
+	WindowClass    string
+	WindowName     string
+	ElapsedSecs    int64
+	OnDesktop      bool
+	Paused         bool
+	ScreenTimeSecs int64
+	ProgramStats   []*activity.ProgramStat
 }
 
 type Manager struct {
 	PageCache        map[string]*template.Template
-	DocCache         map[string]*template.Template
+	Docs             map[string]*Doc
+	DocList          []*Doc
 	PartialsTemplate *template.Template
 }
 
@@ -62,7 +137,7 @@ func NewManager() (*Manager, error) {
 		return nil, err
 	}
 
-	docCache, err := newDocCache()
+	docs, docList, err := newDocs()
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +149,8 @@ func NewManager() (*Manager, error) {
 
 	return &Manager{
 		PageCache:        pageCache,
-		DocCache:         docCache,
+		Docs:             docs,
+		DocList:          docList,
 		PartialsTemplate: partialTemplate,
 	}, nil
 }
@@ -92,15 +168,6 @@ func newPageCache() (map[string]*template.Template, error) {
 	)
 }
 
-func newDocCache() (map[string]*template.Template, error) {
-	return generateCacheFromGlob(
-		"gohtml/docs/*.gohtml",
-		func(filePath string) []string {
-			return []string{baseTemplatePath, partialsTemplateGlob, "gohtml/pages/docs.gohtml", filePath}
-		},
-	)
-}
-
 // generateCacheFromGlob builds a cache of templates. Every key is derived from
 // a template found in globPattern and every associated value is a template set
 // that contains that template plus the files returned by buildTemplateBits.
@@ -151,17 +218,20 @@ func RenderDoc(
 	docName string,
 	tmplData *Data,
 ) error {
-	tmpl := manager.DocCache[string(docName)]
-	if tmpl == nil {
-		tmpl = manager.PageCache[string(Page404)]
+	doc := manager.Docs[docName]
+	if doc == nil {
+		return RenderPage(manager, w, Page404, tmplData)
 	}
 
-	return writeTemplate(w, tmpl, "base", tmplData)
+	tmplData.Doc = doc
+	tmplData.Docs = manager.DocList
+
+	return RenderPage(manager, w, PageDocs, tmplData)
 }
 
 func RenderPartial(
 	manager *Manager,
-	w http.ResponseWriter,
+	w io.Writer,
 	partialName string,
 	tmplData any,
 ) error {
@@ -177,7 +247,7 @@ func RenderPartial(
 }
 
 func writeTemplate(
-	w http.ResponseWriter,
+	w io.Writer,
 	tmpl *template.Template,
 	templateName string,
 	tmplData any,
@@ -1,7 +1,11 @@
 package templates
 
 import (
+	"regexp"
+	"strings"
 	"testing"
+
+	"github.com/bnuredini/telltime/ui"
 )
 
 func TestFormatSecs(t *testing.T) {
@@ -26,3 +30,106 @@ func TestFormatSecs(t *testing.T) {
 		}
 	}
 }
+
+func TestNewDocs(t *testing.T) {
+	docs, list, err := newDocs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{DocIndex, "configuration", "privacy", "api"} {
+		if docs[name] == nil {
+			t.Errorf("missing the %s doc", name)
+		}
+	}
+	if len(list) != len(docs) || list[0].Name != DocIndex {
+		t.Errorf("got %d docs in the list starting with %q, want %d starting with the index", len(list), list[0].Name, len(docs))
+	}
+
+	config := string(docs["configuration"].HTML)
+	for _, want := range []string{"<table>", "<code>-day-start-hour</code>", "<code>-record-window-titles</code>"} {
+		if !strings.Contains(config, want) {
+			t.Errorf("the configuration doc doesn't contain %q", want)
+		}
+	}
+}
+
+func TestNewDocTitle(t *testing.T) {
+	doc, err := newDoc("privacy", []byte("Intro\n\n# Privacy matters\n\n## Details\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Title, "Privacy matters"; got != want {
+		t.Errorf("got title %q, want %q", got, want)
+	}
+	if !strings.Contains(string(doc.HTML), `<h2 id="details">Details</h2>`) {
+		t.Errorf("got %s, want a heading with an ID", doc.HTML)
+	}
+
+	doc, err = newDoc("untitled", []byte("No heading"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Title, "untitled"; got != want {
+		t.Errorf("got title %q, want %q", got, want)
+	}
+}
+
+func TestNewYearHeatmap(t *testing.T) {
+	// 2025 starts on a Wednesday.
+	heatmap := NewYearHeatmap(2025, map[string]int64{"2025-01-01": 3600, "2025-03-10": 900, "2024-12-31": 7200})
+
+	if heatmap.GapDays != 2 || len(heatmap.Days) != 365 {
+		t.Fatalf("got %d gap days and %d days, want 2 and 365", heatmap.GapDays, len(heatmap.Days))
+	}
+	if heatmap.TotalSecs != 4500 {
+		t.Errorf("got a total of %ds, want 4500s", heatmap.TotalSecs)
+	}
+	if got := heatmap.Days[0]; got.Date != "2025-01-01" || got.Level != HeatmapLevels || got.Label != "2025-01-01: 1h" {
+		t.Errorf("got %+v for the first day", got)
+	}
+	if got := heatmap.Days[68]; got.Date != "2025-03-10" || got.Level != 1 {
+		t.Errorf("got %+v for 2025-03-10", got)
+	}
+	if got := heatmap.Days[1]; got.Level != 0 {
+		t.Errorf("got level %d for a day without time", got.Level)
+	}
+}
+
+func TestHeatmapLevel(t *testing.T) {
+	tests := []struct {
+		secs, maxSecs int64
+		want          int
+	}{
+		{0, 100, 0},
+		{1, 100, 1},
+		{25, 100, 1},
+		{26, 100, 2},
+		{75, 100, 3},
+		{100, 100, 4},
+		{10, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := heatmapLevel(tt.secs, tt.maxSecs); got != tt.want {
+			t.Errorf("secs=%d, maxSecs=%d: got %d, want %d", tt.secs, tt.maxSecs, got, tt.want)
+		}
+	}
+}
+
+func TestExternalScriptsHaveIntegrity(t *testing.T) {
+	base, err := ui.Files.ReadFile(baseTemplatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scripts := regexp.MustCompile(`<script [^>]*src="https://[^>]*>`).FindAllString(string(base), -1)
+	if len(scripts) == 0 {
+		t.Fatal("found no external scripts")
+	}
+	for _, script := range scripts {
+		if !strings.Contains(script, `integrity="sha384-`) {
+			t.Errorf("%s has no integrity hash", script)
+		}
+	}
+}
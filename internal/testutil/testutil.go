@@ -0,0 +1,31 @@
+// Package testutil has the fixtures that the tests of several packages share.
+package testutil
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/bnuredini/telltime/internal/migrations"
+
+	_ "modernc.org/sqlite"
+)
+
+// OpenDB returns an in-memory database with every migration applied. It's
+// closed when the test ends.
+func OpenDB(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: gets its own database.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err = migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
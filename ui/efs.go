@@ -2,5 +2,5 @@ package ui
 
 import "embed"
 
-//go:embed "gohtml" "static"
+//go:embed "docs" "gohtml" "static"
 var Files embed.FS